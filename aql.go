@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	driver "github.com/arangodb/go-driver"
+)
+
+// The aql testcase runs the queries of the comma separated files
+// -aqlFiles, one query per file, taking turns. The bind parameters are
+// produced by the comma separated generators of -aqlBindVars, each of
+// the form name=generator:
+//
+//	key               a seeded key "K<n>" chosen by -keyDistribution,
+//	                  uniformly without one
+//	range:<a>:<b>     a random integer from a to b
+//	list:<x>|<y>|...  one of the values at random
+//	<value>           the value itself, as JSON if it parses
+//
+// e.g. -aqlBindVars=key=key,limit=range:1:100,color=list:red|green, so
+// values cannot contain commas. A query only gets the parameters it
+// uses, @@col is the test collection.
+// The cursor options are -aqlBatchSize, -aqlTTL, -aqlMemoryLimit,
+// -aqlFullCount and -aqlProfile. Besides the latencies of all queries
+// together, the latencies and the server execution times of every query
+// are reported separately.
+
+var (
+	aqlFiles       string        = "" // comma separated files with one query each
+	aqlBindVars    string        = "" // comma separated name=generator pairs
+	aqlBatchSize   int           = 0  // cursor batch size, 0 for the server default
+	aqlTTL         time.Duration = 0  // cursor time to live, 0 for the server default
+	aqlMemoryLimit int64         = 0  // memory limit of queries in bytes, 0 for none
+	aqlFullCount   bool          = false
+	aqlProfile     int           = 0 // profiling level, 0 for none
+)
+
+// bindVarGenerator produces the value of a bind parameter.
+type bindVarGenerator func(r *rand.Rand) interface{}
+
+// parseBindVars parses -aqlBindVars.
+func parseBindVars(spec string) map[string]bindVarGenerator {
+	gens := map[string]bindVarGenerator{}
+	var keys keyChooser
+	for _, part := range strings.Split(spec, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		pos := strings.Index(part, "=")
+		if pos < 0 {
+			log.Fatalf("Invalid -aqlBindVars entry %s, needs name=generator", part)
+		}
+		name, gen := strings.TrimSpace(part[:pos]), part[pos+1:]
+		switch {
+		case gen == "key":
+			if keys == nil {
+				dist := keyDistribution
+				if dist == "sequential" {
+					dist = "uniform"
+				}
+				n := keySpace
+				if n <= 0 {
+					n = nrRequests
+				}
+				keys = newKeyChooser(dist, n, nil)
+			}
+			gens[name] = func(r *rand.Rand) interface{} {
+				return "K" + strconv.Itoa(keys.next(r))
+			}
+		case strings.HasPrefix(gen, "range:"):
+			bounds := strings.Split(gen[len("range:"):], ":")
+			var from, to int
+			var err error
+			if len(bounds) == 2 {
+				if from, err = strconv.Atoi(bounds[0]); err == nil {
+					to, err = strconv.Atoi(bounds[1])
+				}
+			}
+			if len(bounds) != 2 || err != nil || to < from {
+				log.Fatalf("Invalid range in -aqlBindVars entry %s", part)
+			}
+			gens[name] = func(r *rand.Rand) interface{} {
+				return from + r.Intn(to-from+1)
+			}
+		case strings.HasPrefix(gen, "list:"):
+			var values []interface{}
+			for _, v := range strings.Split(gen[len("list:"):], "|") {
+				values = append(values, jsonValue(v))
+			}
+			gens[name] = func(r *rand.Rand) interface{} {
+				return values[r.Intn(len(values))]
+			}
+		default:
+			value := jsonValue(gen)
+			gens[name] = func(r *rand.Rand) interface{} {
+				return value
+			}
+		}
+	}
+	return gens
+}
+
+// jsonValue returns s parsed as JSON, or s itself if it does not parse.
+func jsonValue(s string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return s
+	}
+	return v
+}
+
+// bindParameters returns the bind parameters which query uses, once
+// each and with the @@ of collections. An @ in a string, a quoted name or
+// a comment is no bind parameter.
+func bindParameters(query string) []string {
+	var vars []string
+	seen := map[string]bool{}
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '"' || c == '\'' || c == '`':
+			// Up to the closing quote, skipping escaped characters.
+			for i++; i < len(query) && query[i] != c; i++ {
+				if query[i] == '\\' {
+					i++
+				}
+			}
+		case strings.HasPrefix(query[i:], "´"):
+			end := strings.Index(query[i+len("´"):], "´")
+			if end < 0 {
+				return vars
+			}
+			i += end + 2*len("´") - 1
+		case strings.HasPrefix(query[i:], "//"):
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return vars
+			}
+			i += end + 3
+		case c == '@':
+			j := i + 1
+			if j < len(query) && query[j] == '@' {
+				j++
+			}
+			k := j
+			for k < len(query) && isNameChar(query[k]) {
+				k++
+			}
+			if k > j && !seen[query[i:k]] {
+				seen[query[i:k]] = true
+				vars = append(vars, query[i:k])
+			}
+			i = k - 1
+		}
+	}
+	return vars
+}
+
+func isNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
+
+// usesBindParameter tells whether query uses the bind parameter v, given
+// with @ or @@.
+func usesBindParameter(query string, v string) bool {
+	for _, u := range bindParameters(query) {
+		if u == v {
+			return true
+		}
+	}
+	return false
+}
+
+// aqlQuery is one of the queries of the aql testcase.
+type aqlQuery struct {
+	name  string
+	query string
+	vars  []string // bind parameters used, with the @@ of collections
+}
+
+func readAQLQueries(files string) []aqlQuery {
+	var queries []aqlQuery
+	for _, file := range strings.Split(files, ",") {
+		if file == "" {
+			continue
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.Fatalf("Failed to read query: %v", err)
+		}
+		q := aqlQuery{
+			name:  strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)),
+			query: string(data),
+		}
+		q.vars = bindParameters(q.query)
+		queries = append(queries, q)
+	}
+	if len(queries) == 0 {
+		log.Fatalf("-testcase=aql needs -aqlFiles")
+	}
+	return queries
+}
+
+// aqlContext applies the cursor options.
+func aqlContext() context.Context {
+	ctx := context.Background()
+	if aqlBatchSize > 0 {
+		ctx = driver.WithQueryBatchSize(ctx, aqlBatchSize)
+	}
+	if aqlTTL > 0 {
+		ctx = driver.WithQueryTTL(ctx, aqlTTL)
+	}
+	if aqlMemoryLimit > 0 {
+		ctx = driver.WithQueryMemoryLimit(ctx, aqlMemoryLimit)
+	}
+	if aqlFullCount {
+		ctx = driver.WithQueryFullCount(ctx)
+	}
+	if aqlProfile > 0 {
+		ctx = driver.WithQueryProfile(ctx, aqlProfile)
+	}
+	return ctx
+}
+
+func aqlWorkload(col driver.Collection) workload {
+	db := col.Database()
+	queries := readAQLQueries(aqlFiles)
+	gens := parseBindVars(aqlBindVars)
+	for _, q := range queries {
+		for _, v := range q.vars {
+			if _, ok := gens[v[1:]]; !ok && v != "@@col" {
+				log.Fatalf("Query %s uses %s which -aqlBindVars does not give", q.name, v)
+			}
+		}
+	}
+	var rands workerRands
+	var ops opTimes
+
+	op := func(base, i int) error {
+		r := rands.get(base)
+		q := queries[(base+i)%len(queries)]
+		bindVars := map[string]interface{}{}
+		for _, v := range q.vars {
+			if v == "@@col" {
+				bindVars["@col"] = col.Name()
+			} else {
+				bindVars[v[1:]] = gens[v[1:]](r)
+			}
+		}
+		startTime := time.Now()
+		cur, err := db.Query(aqlContext(), q.query, bindVars)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+		for {
+			var v interface{}
+			if _, err = cur.ReadDocument(nil, &v); err != nil {
+				if !driver.IsNoMoreDocuments(err) {
+					return err
+				}
+				break
+			}
+		}
+		ops.add(q.name, time.Since(startTime))
+		ops.add(q.name+" server execution", cur.Statistics().ExecutionTime())
+		return nil
+	}
+	done := func(par int) {
+		ops.log("aql", "aql", par)
+	}
+	return workload{"aql", "aql query ops", op, done}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBindParameters(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"FOR d IN @@col FILTER d._key == @key RETURN d", []string{"@@col", "@key"}},
+		{"FOR d IN @@col LIMIT @n, @n RETURN d", []string{"@@col", "@n"}},
+		{`FOR d IN @@col FILTER d.email LIKE "%@example.com" RETURN d`, []string{"@@col"}},
+		{`RETURN ['it''s @a', 'a\'@b', "\"@c"]`, nil},
+		{"RETURN d.`@attr` + d.´@attr´ + @v", []string{"@v"}},
+		{"// @x is not used\nRETURN @y /* nor @z */ + @w", []string{"@y", "@w"}},
+		{"RETURN @y /* @z", []string{"@y"}},
+		{"RETURN @name", []string{"@name"}},
+		{"RETURN @ + 1", nil},
+	}
+	for _, tt := range tests {
+		if got := bindParameters(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("bindParameters(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestUsesBindParameter(t *testing.T) {
+	tests := []struct {
+		query, v string
+		want     bool
+	}{
+		{"FOR i IN 1..@n RETURN i", "@n", true},
+		{"FOR i IN 1..@name RETURN i", "@n", false},
+		{"FOR d IN @@col RETURN d", "@@col", true},
+		{"FOR d IN @@col RETURN d", "@col", false},
+		{`RETURN "@n"`, "@n", false},
+	}
+	for _, tt := range tests {
+		if got := usesBindParameter(tt.query, tt.v); got != tt.want {
+			t.Errorf("usesBindParameter(%q, %q) = %v, want %v", tt.query, tt.v, got, tt.want)
+		}
+	}
+}
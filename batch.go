@@ -0,0 +1,136 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	driver "github.com/arangodb/go-driver"
+)
+
+// The batch testcases postDocsBatch, seedDocsBatch, readDocsBatch and
+// replaceDocsBatch work like their single document counterparts but
+// handle -batchSize documents per request with the multi-document API.
+// -nrRequests is the number of batches, seedDocsBatch therefore seeds the
+// keys "K0" to "K<nrRequests*batchSize-1>". Besides the usual statistics
+// per request they report the latencies per document, that is the
+// request latencies divided by the batch size, and the documents per
+// second.
+
+var batchSize int = 100 // documents per request of the batch testcases
+
+// batchStats collects the per document numbers of a batch workload.
+type batchStats struct {
+	once  sync.Once
+	start time.Time
+	ops   opTimes
+}
+
+// measure runs the request f and records its latency per document.
+func (b *batchStats) measure(f func() error) error {
+	b.once.Do(func() { b.start = time.Now() })
+	startTime := time.Now()
+	err := f()
+	if err == nil {
+		b.ops.add("per document", time.Since(startTime)/time.Duration(batchSize))
+	}
+	return err
+}
+
+// done reports the per document numbers after a run by par workers.
+func (b *batchStats) done(tc string, name string) func(par int) {
+	return func(par int) {
+		elapsed := time.Since(b.start)
+		b.ops.log(tc, name, par)
+		b.ops.mutex.Lock()
+		docs := len(b.ops.times["per document"]) * batchSize
+		b.ops.mutex.Unlock()
+		if measuring && elapsed > 0 {
+			log.Printf("%s: %d documents in %v, %.0f documents/s",
+				name, docs, elapsed, float64(docs)/elapsed.Seconds())
+		}
+	}
+}
+
+// batchKeys returns the keys of the documents of batch number n.
+func batchKeys(n int) []string {
+	keys := make([]string, batchSize)
+	for j := range keys {
+		keys[j] = "K" + strconv.Itoa(n*batchSize+j)
+	}
+	return keys
+}
+
+// batchError returns the first error of a multi-document request.
+func batchError(errs driver.ErrorSlice, err error) error {
+	if err != nil {
+		return err
+	}
+	return errs.FirstNonNil()
+}
+
+func postDocsBatchWorkload(col driver.Collection) workload {
+	docs := newDocSource()
+	stats := &batchStats{}
+	return workload{"postDocsBatch", "create document batch ops", func(base, i int) error {
+		batch := make([]interface{}, batchSize)
+		for j := range batch {
+			batch[j] = docs.document(base, i*batchSize+j, "")
+		}
+		return stats.measure(func() error {
+			_, errs, err := col.CreateDocuments(insertContext(), batch)
+			return batchError(errs, err)
+		})
+	}, stats.done("postDocsBatch", "create document batch")}
+}
+
+func seedDocsBatchWorkload(col driver.Collection) workload {
+	docs := newDocSource()
+	stats := &batchStats{}
+	return workload{"seedDocsBatch", "seed document batch ops", func(base, i int) error {
+		keys := batchKeys(base + i)
+		batch := make([]interface{}, batchSize)
+		for j, key := range keys {
+			batch[j] = docs.document(base, i*batchSize+j, key)
+		}
+		return stats.measure(func() error {
+			_, errs, err := col.CreateDocuments(insertContext(), batch)
+			return batchError(errs, err)
+		})
+	}, stats.done("seedDocsBatch", "seed document batch")}
+}
+
+func readDocsBatchWorkload(col driver.Collection) workload {
+	keys := newDocKeys(nrRequests * batchSize)
+	stats := &batchStats{}
+	return workload{"readDocsBatch", "read document batch ops", func(base, i int) error {
+		batch := make([]string, batchSize)
+		for j := range batch {
+			batch[j] = keys.key(base, (base+i)*batchSize+j)
+		}
+		books := make([]Book, batchSize)
+		return stats.measure(func() error {
+			_, errs, err := col.ReadDocuments(nil, batch, books)
+			return batchError(errs, err)
+		})
+	}, stats.done("readDocsBatch", "read document batch")}
+}
+
+func replaceDocsBatchWorkload(col driver.Collection) workload {
+	keys := newDocKeys(nrRequests * batchSize)
+	docs := newDocSource()
+	stats := &batchStats{}
+	return workload{"replaceDocsBatch", "replace document batch ops", func(base, i int) error {
+		batch := make([]string, batchSize)
+		replacements := make([]interface{}, batchSize)
+		for j := range batch {
+			batch[j] = keys.key(base, (base+i)*batchSize+j)
+			replacements[j] = docs.document(base, i*batchSize+j, "")
+		}
+		return stats.measure(func() error {
+			_, errs, err := col.ReplaceDocuments(writeContext(), batch, replacements)
+			return batchError(errs, err)
+		})
+	}, stats.done("replaceDocsBatch", "replace document batch")}
+}
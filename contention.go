@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	driver "github.com/arangodb/go-driver"
+)
+
+// The contention testcase lets all workers increment counters in a small
+// set of -contentionKeys shared documents "C0", "C1", ... of the test
+// collection, chosen uniformly or by -keyDistribution. With
+// -contentionMode=ifMatch a request reads a counter and writes it back
+// incremented with the revision it read as If-Match header, with
+// -contentionMode=aql it does the same in one AQL UPDATE with
+// ignoreRevs: false. Either way an increment fails with a conflict (error
+// 1200) if another worker changed the document in between, which is
+// counted and not retried. Latencies of successful and conflicting
+// increments are reported separately, and in the end the counters are
+// checked against the successful increments.
+
+var (
+	contentionKeys int    = 1         // shared documents
+	contentionMode string = "ifMatch" // or "aql"
+)
+
+const contentionQuery = "LET d = DOCUMENT(@@col, @key) " +
+	"UPDATE d WITH {counter: d.counter + 1} IN @@col OPTIONS {ignoreRevs: false}"
+
+type counterDoc struct {
+	Key     string `json:"_key,omitempty"`
+	Counter int64  `json:"counter"`
+}
+
+// isWriteConflict tells whether err is error 1200, which the server
+// returns for If-Match and _rev mismatches as well as for write-write
+// conflicts in RocksDB.
+func isWriteConflict(err error) bool {
+	return driver.IsArangoErrorWithErrorNum(err, 1200) || driver.IsPreconditionFailed(err)
+}
+
+func contentionWorkload(col driver.Collection) workload {
+	if contentionKeys < 1 {
+		log.Fatalf("-contentionKeys needs to be at least 1")
+	}
+	if contentionMode != "ifMatch" && contentionMode != "aql" {
+		log.Fatalf("-contentionMode needs to be ifMatch or aql")
+	}
+	db := col.Database()
+	for k := 0; k < contentionKeys; k++ {
+		doc := counterDoc{Key: "C" + strconv.Itoa(k)}
+		ctx := driver.WithOverwriteMode(insertContext(), driver.OverwriteModeReplace)
+		if _, err := col.CreateDocument(ctx, doc); err != nil {
+			log.Fatalf("Failed to create counter: %v", err)
+		}
+	}
+
+	dist := keyDistribution
+	if dist == "sequential" {
+		dist = "uniform"
+	}
+	keys := newKeyChooser(dist, contentionKeys, nil)
+	var rands workerRands
+	var ops opTimes
+	succeeded, conflicts := int64(0), int64(0)
+
+	increment := func(key string) error {
+		if contentionMode == "aql" {
+			bindVars := map[string]interface{}{"@col": col.Name(), "key": key}
+			cur, err := db.Query(writeContext(), contentionQuery, bindVars)
+			if err == nil {
+				cur.Close()
+			}
+			return err
+		}
+		var doc counterDoc
+		meta, err := col.ReadDocument(nil, key, &doc)
+		if err != nil {
+			return err
+		}
+		ctx := driver.WithRevision(writeContext(), meta.Rev)
+		_, err = col.UpdateDocument(ctx, key, counterDoc{Counter: doc.Counter + 1})
+		return err
+	}
+	op := func(base, i int) error {
+		key := "C" + strconv.Itoa(keys.next(rands.get(base)))
+		startTime := time.Now()
+		err := increment(key)
+		if isWriteConflict(err) {
+			ops.add("conflict", time.Since(startTime))
+			atomic.AddInt64(&conflicts, 1)
+			return nil
+		}
+		if err == nil {
+			ops.add("success", time.Since(startTime))
+			atomic.AddInt64(&succeeded, 1)
+		}
+		return err
+	}
+	done := func(par int) {
+		ops.log("contention", "contention "+contentionMode, par)
+		total := succeeded + conflicts
+		if !measuring || total == 0 {
+			return
+		}
+		log.Printf("Contention on %d keys: %d increments, %.2f%% succeeded, %.2f%% conflicts",
+			contentionKeys, total, float64(succeeded)*100/float64(total), float64(conflicts)*100/float64(total))
+		query := "FOR d IN @@col FILTER d._key IN @keys COLLECT AGGREGATE s = SUM(d.counter) RETURN s"
+		keyList := make([]string, contentionKeys)
+		for k := range keyList {
+			keyList[k] = "C" + strconv.Itoa(k)
+		}
+		cur, err := db.Query(context.Background(), query, map[string]interface{}{"@col": col.Name(), "keys": keyList})
+		if err != nil {
+			log.Fatalf("Failed to sum counters: %v", err)
+		}
+		defer cur.Close()
+		var sum int64
+		if _, err = cur.ReadDocument(nil, &sum); err != nil {
+			log.Fatalf("Failed to sum counters: %v", err)
+		}
+		if sum != succeeded {
+			log.Printf("Counters sum up to %d instead of %d, increments were lost", sum, succeeded)
+		}
+	}
+	return workload{"contention", "contention increment ops", op, done}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	driver "github.com/arangodb/go-driver"
+)
+
+// The cursor testcase retrieves large query results, -cursorQuery with
+// @n set to -cursorResults, in batches of -cursorBatchSize documents,
+// with -cursorStream as a streaming cursor. -cursorLoop selects how the
+// results are read: "noMoreDocuments" reads until ReadDocument fails with
+// a NoMoreDocuments error, the usual loop of driver v1, "hasMore" asks
+// HasMore before every read like driver v2 code does. Besides the
+// latency of whole queries, the time to the first batch and the latency
+// of every further batch are reported, as well as the amount of data,
+// measured as JSON, and its rate.
+
+var (
+	cursorQuery     string = `FOR i IN 1..@n RETURN {i, s: CONCAT("value", i)}`
+	cursorResults   int    = 100000            // value of @n
+	cursorBatchSize int    = 1000              // documents per batch
+	cursorStream    bool   = false             // use a streaming cursor
+	cursorLoop      string = "noMoreDocuments" // can be "hasMore"
+)
+
+func cursorWorkload(col driver.Collection) workload {
+	if cursorLoop != "noMoreDocuments" && cursorLoop != "hasMore" {
+		log.Fatalf("-cursorLoop needs to be noMoreDocuments or hasMore")
+	}
+	if cursorBatchSize < 1 {
+		log.Fatalf("-cursorBatchSize needs to be at least 1")
+	}
+	db := col.Database()
+	bindVars := map[string]interface{}{}
+	if usesBindParameter(cursorQuery, "@@col") {
+		bindVars["@col"] = col.Name()
+	}
+	if usesBindParameter(cursorQuery, "@n") {
+		bindVars["n"] = cursorResults
+	}
+	ctx := driver.WithQueryBatchSize(context.Background(), cursorBatchSize)
+	if cursorStream {
+		ctx = driver.WithQueryStream(ctx)
+	}
+	var ops opTimes
+	var docs, batches, bytes int64
+	var once sync.Once
+	var start time.Time
+
+	op := func(base, i int) error {
+		once.Do(func() { start = time.Now() })
+		startTime := time.Now()
+		cur, err := db.Query(ctx, cursorQuery, bindVars)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+		ops.add("first batch", time.Since(startTime))
+		var n, size int64
+		for {
+			if cursorLoop == "hasMore" && !cur.HasMore() {
+				break
+			}
+			// Documents are read as JSON, which measures their size
+			// without keeping them.
+			var v json.RawMessage
+			readStart := time.Now()
+			_, err = cur.ReadDocument(nil, &v)
+			if driver.IsNoMoreDocuments(err) && cursorLoop == "noMoreDocuments" {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if n > 0 && n%int64(cursorBatchSize) == 0 {
+				// This read had to fetch the next batch.
+				ops.add("next batch", time.Since(readStart))
+			}
+			n++
+			size += int64(len(v))
+		}
+		atomic.AddInt64(&docs, n)
+		atomic.AddInt64(&batches, (n+int64(cursorBatchSize)-1)/int64(cursorBatchSize))
+		atomic.AddInt64(&bytes, size)
+		return nil
+	}
+	done := func(par int) {
+		ops.log("cursor", "cursor", par)
+		elapsed := time.Since(start)
+		if !measuring || elapsed <= 0 {
+			return
+		}
+		log.Printf("Cursor: %d documents in %d batches, %.2f MB of JSON, %.2f MB/s",
+			docs, batches, float64(bytes)/1e6, float64(bytes)/1e6/elapsed.Seconds())
+	}
+	return workload{"cursor", "cursor query ops (" + cursorLoop + ")", op, done}
+}
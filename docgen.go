@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"math/rand"
+	"strconv"
+)
+
+// The document generator replaces the small Book documents of the write
+// testcases once -docSize is set. Documents get -docAttributes attributes
+// "a0", "a1", ... per level; with -docDepth the last attribute of each
+// level is another object, down to the given depth, and with
+// -docArrayLength every second attribute is an array of that many
+// strings instead of a single string. The strings are sized such that
+// the JSON of a document has about the drawn size:
+//
+//	fixed      always -docSize bytes
+//	uniform    between -docSize and -docSizeMax bytes
+//	lognormal  median -docSize bytes, spread by -docSizeSigma
+//
+// -docEntropy is the fraction of random characters in the strings, the
+// rest repeats a single character and compresses well.
+
+var (
+	docSize             int     = 0       // approximate document size in bytes, 0 for Book documents
+	docSizeDistribution string  = "fixed" // fixed, uniform or lognormal
+	docSizeMax          int     = 0       // maximal size for the uniform distribution
+	docSizeSigma        float64 = 0.5     // sigma for the lognormal distribution
+	docAttributes       int     = 4       // attributes per object
+	docDepth            int     = 0       // nesting depth of objects
+	docArrayLength      int     = 0       // length of array attributes, 0 for none
+	docEntropy          float64 = 1       // fraction of random characters in strings
+)
+
+// docGenerator builds documents of the configured shape.
+type docGenerator struct {
+	overhead int // JSON size of a document with empty strings
+	strings  int // number of strings per document
+}
+
+func newDocGenerator() *docGenerator {
+	switch docSizeDistribution {
+	case "fixed", "uniform", "lognormal":
+	default:
+		log.Fatalf("-docSizeDistribution needs to be fixed, uniform or lognormal")
+	}
+	if docSizeDistribution == "uniform" && docSizeMax < docSize {
+		log.Fatalf("-docSizeMax needs to be at least -docSize")
+	}
+	if docAttributes < 1 {
+		log.Fatalf("-docAttributes needs to be at least 1")
+	}
+	g := &docGenerator{}
+	empty, err := json.Marshal(g.object(nil, 0, 0, &g.strings))
+	if err != nil {
+		log.Fatalf("Failed to generate document: %v", err)
+	}
+	g.overhead = len(empty)
+	return g
+}
+
+// size draws the size of the next document.
+func (g *docGenerator) size(r *rand.Rand) int {
+	switch docSizeDistribution {
+	case "uniform":
+		return docSize + r.Intn(docSizeMax-docSize+1)
+	case "lognormal":
+		return int(float64(docSize) * math.Exp(docSizeSigma*r.NormFloat64()))
+	}
+	return docSize
+}
+
+// object builds one level of a document with strings of length n, it
+// counts the strings in count.
+func (g *docGenerator) object(r *rand.Rand, n int, depth int, count *int) map[string]interface{} {
+	obj := make(map[string]interface{}, docAttributes)
+	for a := 0; a < docAttributes; a++ {
+		name := "a" + strconv.Itoa(a)
+		switch {
+		case a == docAttributes-1 && depth < docDepth:
+			obj[name] = g.object(r, n, depth+1, count)
+		case a%2 == 1 && docArrayLength > 0:
+			arr := make([]string, docArrayLength)
+			for j := range arr {
+				arr[j] = randomString(r, n)
+			}
+			obj[name] = arr
+			*count += docArrayLength
+		default:
+			obj[name] = randomString(r, n)
+			*count++
+		}
+	}
+	return obj
+}
+
+// document generates a document with the given key, if it is not empty.
+func (g *docGenerator) document(r *rand.Rand, key string) map[string]interface{} {
+	doc, _ := g.documentSize(r, key)
+	return doc
+}
+
+// documentSize is document which also returns the size of the JSON of
+// the document, known from its shape without encoding it.
+func (g *docGenerator) documentSize(r *rand.Rand, key string) (map[string]interface{}, int) {
+	n := (g.size(r) - g.overhead) / g.strings
+	if n < 1 {
+		n = 1
+	}
+	var count int
+	doc := g.object(r, n, 0, &count)
+	if key != "" {
+		doc["_key"] = key
+	}
+	return doc, g.overhead + count*n + keySize(key)
+}
+
+// keySize is what the attribute _key with the given key, if it is not
+// empty, adds to the JSON of a document. Keys need no escaping.
+func keySize(key string) int {
+	if key == "" {
+		return 0
+	}
+	return len(`"_key":"",`) + len(key)
+}
+
+// randomString returns a string of length n of which docEntropy is
+// random, or an empty string without r.
+func randomString(r *rand.Rand, n int) string {
+	if r == nil {
+		return ""
+	}
+	b := make([]byte, n)
+	random := int(math.Round(float64(n) * docEntropy))
+	for i := range b {
+		if i < random {
+			b[i] = ycsbChars[r.Intn(len(ycsbChars))]
+		} else {
+			b[i] = 'x'
+		}
+	}
+	return string(b)
+}
+
+// docSource makes the documents of the write testcases, Books unless
+// -docSize is set.
+type docSource struct {
+	gen      *docGenerator
+	rands    workerRands
+	bookSize int // JSON size of a Book without key and with 0 pages
+}
+
+func newDocSource() *docSource {
+	s := &docSource{}
+	book, _ := json.Marshal(Book{Title: "Some small string"})
+	s.bookSize = len(book)
+	if docSize > 0 {
+		s.gen = newDocGenerator()
+		r := rand.New(rand.NewSource(1))
+		var total int
+		for i := 0; i < 100; i++ {
+			data, _ := json.Marshal(s.gen.document(r, ""))
+			total += len(data)
+		}
+		log.Printf("Generating documents of %d bytes on average", total/100)
+	}
+	return s
+}
+
+// document returns the document number i of the worker with base.
+func (s *docSource) document(base, i int, key string) interface{} {
+	if s.gen == nil {
+		return Book{
+			Key:     key,
+			Title:   "Some small string",
+			NoPages: i,
+		}
+	}
+	return s.gen.document(s.rands.get(base), key)
+}
+
+// documentSize is document which also returns the size of the JSON of
+// the document.
+func (s *docSource) documentSize(base, i int, key string) (interface{}, int) {
+	if s.gen == nil {
+		return s.document(base, i, key), s.bookSize - 1 + len(strconv.Itoa(i)) + keySize(key)
+	}
+	return s.gen.documentSize(s.rands.get(base), key)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	driver "github.com/arangodb/go-driver"
+)
+
+// Durability settings of the collections and of the writes. They are
+// part of the exported tags and of the report data, so that runs which
+// sweep over them can be told apart.
+
+var (
+	collectionWaitForSync bool   = false // create collections with waitForSync
+	waitForSync           bool   = false // send waitForSync with every write
+	writeConcern          int    = 0     // write concern of collections, 0 for the server default
+	numberOfShards        int    = 0     // shards of collections, 0 for the server default
+	overwriteMode         string = ""    // overwrite mode of inserts: replace, update, ignore or conflict
+)
+
+// collectionOptions are the options for the collections gobench creates.
+func collectionOptions() driver.CreateCollectionOptions {
+	opts := driver.CreateCollectionOptions{
+		ReplicationFactor: replFactor,
+		WriteConcern:      writeConcern,
+		NumberOfShards:    numberOfShards,
+		WaitForSync:       collectionWaitForSync,
+
+		ShardKeys:            shardKeyList(),
+		DistributeShardsLike: distributeShardsLike,
+		SmartJoinAttribute:   smartJoinAttribute,
+		IsSmart:              smart,
+		SmartGraphAttribute:  smartGraphAttribute,
+	}
+	if satellite {
+		opts.ReplicationFactor = driver.ReplicationFactorSatellite
+		opts.WriteConcern = 0
+		opts.NumberOfShards = 0
+	}
+	return opts
+}
+
+// checkCollectionOptions warns if col, which existed already, was
+// created with other options than collectionOptions would give it, since
+// the results are tagged with the latter. Properties a single server does
+// not report are not compared.
+func checkCollectionOptions(col driver.Collection) {
+	props, err := col.Properties(nil)
+	if err != nil {
+		log.Fatalf("Failed to read collection properties: %v", err)
+	}
+	opts := collectionOptions()
+	differs := func(what string, want, have interface{}) {
+		log.Printf("Warning: collection %s exists with %s %v instead of %v, results are tagged with the latter",
+			col.Name(), what, have, want)
+	}
+	if props.ReplicationFactor != 0 && props.ReplicationFactor != opts.ReplicationFactor {
+		differs("replicationFactor", opts.ReplicationFactor, props.ReplicationFactor)
+	}
+	if opts.WriteConcern > 0 && props.WriteConcern != 0 && props.WriteConcern != opts.WriteConcern {
+		differs("writeConcern", opts.WriteConcern, props.WriteConcern)
+	}
+	if opts.NumberOfShards > 0 && props.NumberOfShards != 0 && props.NumberOfShards != opts.NumberOfShards {
+		differs("numberOfShards", opts.NumberOfShards, props.NumberOfShards)
+	}
+	if props.WaitForSync != opts.WaitForSync {
+		differs("waitForSync", opts.WaitForSync, props.WaitForSync)
+	}
+	if opts.ShardKeys != nil && props.ShardKeys != nil &&
+		strings.Join(props.ShardKeys, ",") != strings.Join(opts.ShardKeys, ",") {
+		differs("shardKeys", opts.ShardKeys, props.ShardKeys)
+	}
+	if opts.DistributeShardsLike != "" && props.DistributeShardsLike != opts.DistributeShardsLike {
+		differs("distributeShardsLike", opts.DistributeShardsLike, props.DistributeShardsLike)
+	}
+}
+
+// writeContext is the context for modifications of documents.
+func writeContext() context.Context {
+	ctx := context.Background()
+	if waitForSync {
+		ctx = driver.WithWaitForSync(ctx)
+	}
+	return ctx
+}
+
+// insertContext is the context for inserts of documents.
+func insertContext() context.Context {
+	ctx := writeContext()
+	if overwriteMode != "" {
+		ctx = driver.WithOverwriteMode(ctx, driver.OverwriteMode(overwriteMode))
+	}
+	return ctx
+}
+
+func checkDurabilityFlags() {
+	switch overwriteMode {
+	case "", "replace", "update", "ignore", "conflict":
+	default:
+		log.Fatalf("-overwriteMode needs to be replace, update, ignore or conflict")
+	}
+	if writeConcern > replFactor {
+		log.Fatalf("-writeConcern must not be larger than -replicationFactor")
+	}
+}
+
+// durabilityName lists the durability settings which differ from the
+// defaults, for titles.
+func durabilityName() string {
+	s := ""
+	if collectionWaitForSync {
+		s += " collectionWaitForSync"
+	}
+	if waitForSync {
+		s += " waitForSync"
+	}
+	if writeConcern > 0 {
+		s += fmt.Sprintf(" writeConcern=%d", writeConcern)
+	}
+	if numberOfShards > 0 {
+		s += fmt.Sprintf(" shards=%d", numberOfShards)
+	}
+	if overwriteMode != "" {
+		s += " overwriteMode=" + overwriteMode
+	}
+	return s
+}
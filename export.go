@@ -0,0 +1,437 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Export of the results into files for time-series databases. With
+// -exportFile the summary of every testcase and a per interval time
+// series (see -exportInterval) are written at the end of the run, either
+// as InfluxDB line protocol or as OpenMetrics text.
+
+// result is the summary of one logStats call.
+type result struct {
+	Testcase    string        `json:"testcase"`
+	Name        string        `json:"name"`
+	Time        time.Time     `json:"time"`
+	Parallelism int           `json:"parallelism"`
+	Samples     int           `json:"samples"`
+	Sum         time.Duration `json:"sum"`
+	Mean        time.Duration `json:"mean"`
+	Median      time.Duration `json:"median"`
+	P90         time.Duration `json:"p90"`
+	P99         time.Duration `json:"p99"`
+	P999        time.Duration `json:"p999"`
+	Min         time.Duration `json:"min"`
+	Max         time.Duration `json:"max"`
+	StdDev      time.Duration `json:"stddev"`
+
+	Histogram []histogramBucket `json:"histogram"`
+	Spectrum  []spectrumPoint   `json:"spectrum"`
+}
+
+// histogramBucket counts the latencies up to Upper, the buckets are
+// spaced logarithmically with ten buckets per decade.
+type histogramBucket struct {
+	Upper time.Duration `json:"upper"`
+	Count int           `json:"count"`
+}
+
+// spectrumPoint is the latency at a percentile.
+type spectrumPoint struct {
+	Percentile float64       `json:"percentile"`
+	Latency    time.Duration `json:"latency"`
+}
+
+// intervalPoint holds what happened to a testcase within one interval.
+type intervalPoint struct {
+	Testcase string        `json:"testcase"`
+	Time     time.Time     `json:"time"` // end of the interval
+	Interval time.Duration `json:"interval"`
+	Workers  int           `json:"workers"`
+	Requests uint64        `json:"requests"`
+	Errors   uint64        `json:"errors"`
+	Mean     time.Duration `json:"mean"`
+	P50      time.Duration `json:"p50"` // estimated from the histogram
+	P99      time.Duration `json:"p99"` // estimated from the histogram
+	Max      time.Duration `json:"max"`
+}
+
+var (
+	resultsMutex sync.Mutex
+	results      []result
+	intervals    []intervalPoint
+
+	serverVersion string = "unknown" // filled in by main for exports and reports
+)
+
+// recordResult keeps the summary of times, which is sorted in place.
+func recordResult(tc string, name string, par int, times []time.Duration) {
+	nr := len(times)
+	if nr == 0 {
+		return
+	}
+	sort.Slice(times, func(a, b int) bool {
+		return int64(times[a]) < int64(times[b])
+	})
+	var sum time.Duration
+	for _, d := range times {
+		sum += d
+	}
+	mean := sum / time.Duration(nr)
+	var sqrdiff float64
+	for _, d := range times {
+		tmp := float64(d - mean)
+		sqrdiff += tmp * tmp
+	}
+	r := result{
+		Testcase:    tc,
+		Name:        name,
+		Time:        time.Now(),
+		Parallelism: par,
+		Samples:     nr,
+		Sum:         sum,
+		Mean:        mean,
+		Median:      times[nr/2],
+		P90:         times[(nr*90)/100],
+		P99:         times[(nr*99)/100],
+		P999:        times[(nr*999)/1000],
+		Min:         times[0],
+		Max:         times[nr-1],
+		StdDev:      time.Duration(math.Sqrt(sqrdiff / float64(nr))),
+		Histogram:   latencyHistogram(times),
+		Spectrum:    percentileSpectrum(times),
+	}
+	resultsMutex.Lock()
+	results = append(results, r)
+	resultsMutex.Unlock()
+}
+
+// histogramBase is the upper bound of the first latencyHistogram bucket.
+const histogramBase = 10 * time.Microsecond
+
+// latencyHistogram counts the sorted times in logarithmic buckets.
+func latencyHistogram(times []time.Duration) []histogramBucket {
+	index := func(d time.Duration) int {
+		if d <= histogramBase {
+			return 0
+		}
+		return int(math.Ceil(10 * math.Log10(float64(d)/float64(histogramBase))))
+	}
+	first := index(times[0])
+	h := make([]histogramBucket, index(times[len(times)-1])-first+1)
+	for i := range h {
+		h[i].Upper = time.Duration(float64(histogramBase) * math.Pow(10, float64(first+i)/10))
+	}
+	for _, d := range times {
+		h[index(d)-first].Count++
+	}
+	return h
+}
+
+// percentileSpectrum returns the latencies of the sorted times at the
+// percentiles 1-2^(-k/4), as far as there are samples, and at 100%.
+func percentileSpectrum(times []time.Duration) []spectrumPoint {
+	nr := len(times)
+	var s []spectrumPoint
+	for k := 0; ; k++ {
+		p := 1 - math.Pow(2, -float64(k)/4)
+		i := int(p * float64(nr))
+		if i >= nr-1 {
+			break
+		}
+		s = append(s, spectrumPoint{Percentile: 100 * p, Latency: times[i]})
+	}
+	return append(s, spectrumPoint{Percentile: 100, Latency: times[nr-1]})
+}
+
+// bucketQuantile estimates quantile q from latency histogram counts by
+// the upper bound of the bucket it falls into. Values in the +Inf bucket
+// are reported as max.
+func bucketQuantile(buckets []uint64, q float64, max float64) float64 {
+	var total uint64
+	for _, c := range buckets {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(total)))
+	var cum uint64
+	for i, c := range buckets {
+		cum += c
+		if cum >= rank {
+			if i < len(latencyBuckets) && latencyBuckets[i] < max {
+				return latencyBuckets[i]
+			}
+			return max
+		}
+	}
+	return max
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// startSampler snapshots the live metrics every interval and turns the
+// differences into intervalPoints. The returned function takes a last
+// snapshot and stops sampling.
+func startSampler(interval time.Duration) func() {
+	prev := snapshotMetrics()
+	prevTime := time.Now()
+	sample := func() {
+		now := time.Now()
+		cur := snapshotMetrics()
+		var points []intervalPoint
+		for tc, m := range cur {
+			p := prev[tc]
+			count := m.count - p.count
+			if count == 0 {
+				continue
+			}
+			buckets := make([]uint64, len(m.buckets))
+			for i := range buckets {
+				buckets[i] = m.buckets[i]
+				if p.buckets != nil {
+					buckets[i] -= p.buckets[i]
+				}
+			}
+			workers := m.workers
+			if workers == 0 {
+				// The testcase finished within this interval.
+				workers = p.workers
+			}
+			points = append(points, intervalPoint{
+				Testcase: tc,
+				Time:     now,
+				Interval: now.Sub(prevTime),
+				Workers:  workers,
+				Requests: count,
+				Errors:   m.errors - p.errors,
+				Mean:     secondsToDuration((m.sum - p.sum) / float64(count)),
+				P50:      secondsToDuration(bucketQuantile(buckets, 0.5, m.maxSecs)),
+				P99:      secondsToDuration(bucketQuantile(buckets, 0.99, m.maxSecs)),
+				Max:      secondsToDuration(m.maxSecs),
+			})
+		}
+		sort.Slice(points, func(a, b int) bool {
+			return points[a].Testcase < points[b].Testcase
+		})
+		resultsMutex.Lock()
+		intervals = append(intervals, points...)
+		resultsMutex.Unlock()
+		prev = cur
+		prevTime = now
+	}
+
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				sample()
+			case <-done:
+				ticker.Stop()
+				sample()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// exportTags are the tags shared by all exported series, as key/value
+// pairs. name tells apart the results of one testcase, like the
+// latencies of its kinds of operations; the intervals have none.
+func exportTags(tc string, name string, par int) [][2]string {
+	return [][2]string{
+		{"testcase", tc},
+		{"name", name},
+		{"protocol", protocol},
+		{"contentType", contentType},
+		{"tls", strconv.FormatBool(usetls)},
+		{"parallelism", strconv.Itoa(par)},
+		{"nrConnections", strconv.Itoa(nrConnections)},
+		{"server_version", serverVersion},
+		{"waitForSync", strconv.FormatBool(waitForSync)},
+		{"collectionWaitForSync", strconv.FormatBool(collectionWaitForSync)},
+		{"writeConcern", strconv.Itoa(writeConcern)},
+		{"numberOfShards", strconv.Itoa(numberOfShards)},
+		{"overwriteMode", overwriteMode},
+		{"shardKeys", shardKeys},
+		{"distributeShardsLike", distributeShardsLike},
+		{"satellite", strconv.FormatBool(satellite)},
+		{"smartJoinAttribute", smartJoinAttribute},
+		{"smartGraphAttribute", smartGraphAttribute},
+	}
+}
+
+// escapeInflux escapes a tag key or value for the line protocol.
+func escapeInflux(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, ",", `\,`, -1)
+	s = strings.Replace(s, "=", `\=`, -1)
+	return strings.Replace(s, " ", `\ `, -1)
+}
+
+func influxTags(tags [][2]string) string {
+	s := ""
+	for _, t := range tags {
+		if t[1] == "" {
+			continue
+		}
+		s += "," + escapeInflux(t[0]) + "=" + escapeInflux(t[1])
+	}
+	return s
+}
+
+func micros(d time.Duration) float64 {
+	return float64(d) / float64(time.Microsecond)
+}
+
+func writeInflux(w io.Writer) {
+	for _, r := range results {
+		fmt.Fprintf(w, "gobench_summary%s samples=%di,mean_us=%.3f,median_us=%.3f,p90_us=%.3f,p99_us=%.3f,p999_us=%.3f,min_us=%.3f,max_us=%.3f,stddev_us=%.3f %d\n",
+			influxTags(exportTags(r.Testcase, r.Name, r.Parallelism)),
+			r.Samples, micros(r.Mean), micros(r.Median), micros(r.P90),
+			micros(r.P99), micros(r.P999), micros(r.Min), micros(r.Max),
+			micros(r.StdDev), r.Time.UnixNano())
+	}
+	for _, p := range intervals {
+		fmt.Fprintf(w, "gobench_interval%s requests=%di,errors=%di,rate=%.3f,mean_us=%.3f,p50_us=%.3f,p99_us=%.3f,max_us=%.3f %d\n",
+			influxTags(exportTags(p.Testcase, "", p.Workers)),
+			p.Requests, p.Errors, float64(p.Requests)/p.Interval.Seconds(),
+			micros(p.Mean), micros(p.P50), micros(p.P99), micros(p.Max),
+			p.Time.UnixNano())
+	}
+}
+
+func openMetricsLabels(tags [][2]string, extra string) string {
+	l := make([]string, 0, len(tags)+1)
+	for _, t := range tags {
+		l = append(l, fmt.Sprintf(`%s="%s"`, t[0], escapeLabel(t[1])))
+	}
+	if extra != "" {
+		l = append(l, extra)
+	}
+	return "{" + strings.Join(l, ",") + "}"
+}
+
+func unixSeconds(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/1e9, 'f', 3, 64)
+}
+
+func writeOpenMetrics(w io.Writer) {
+	results := append([]result(nil), results...)
+	sort.SliceStable(results, func(a, b int) bool {
+		if results[a].Testcase != results[b].Testcase {
+			return results[a].Testcase < results[b].Testcase
+		}
+		if results[a].Name != results[b].Name {
+			return results[a].Name < results[b].Name
+		}
+		return results[a].Parallelism < results[b].Parallelism
+	})
+	fmt.Fprintln(w, "# TYPE gobench_latency_seconds summary")
+	fmt.Fprintln(w, "# HELP gobench_latency_seconds Request latency of a testcase.")
+	for _, r := range results {
+		tags := exportTags(r.Testcase, r.Name, r.Parallelism)
+		ts := unixSeconds(r.Time)
+		for _, q := range []struct {
+			q string
+			d time.Duration
+		}{{"0.5", r.Median}, {"0.9", r.P90}, {"0.99", r.P99}, {"0.999", r.P999}} {
+			fmt.Fprintf(w, "gobench_latency_seconds%s %g %s\n",
+				openMetricsLabels(tags, `quantile="`+q.q+`"`), q.d.Seconds(), ts)
+		}
+		fmt.Fprintf(w, "gobench_latency_seconds_count%s %d %s\n", openMetricsLabels(tags, ""), r.Samples, ts)
+		fmt.Fprintf(w, "gobench_latency_seconds_sum%s %g %s\n", openMetricsLabels(tags, ""), r.Sum.Seconds(), ts)
+	}
+	summaryGauges := []struct {
+		name, help string
+		get        func(r result) time.Duration
+	}{
+		{"gobench_latency_min_seconds", "Smallest request latency of a testcase.", func(r result) time.Duration { return r.Min }},
+		{"gobench_latency_max_seconds", "Largest request latency of a testcase.", func(r result) time.Duration { return r.Max }},
+		{"gobench_latency_stddev_seconds", "Standard deviation of the request latency of a testcase.", func(r result) time.Duration { return r.StdDev }},
+	}
+	for _, g := range summaryGauges {
+		fmt.Fprintf(w, "# TYPE %s gauge\n# HELP %s %s\n", g.name, g.name, g.help)
+		for _, r := range results {
+			fmt.Fprintf(w, "%s%s %g %s\n", g.name,
+				openMetricsLabels(exportTags(r.Testcase, r.Name, r.Parallelism), ""),
+				g.get(r).Seconds(), unixSeconds(r.Time))
+		}
+	}
+
+	// The interval series are grouped by metric family and label set, so
+	// that the timestamps within every series increase.
+	points := append([]intervalPoint(nil), intervals...)
+	sort.SliceStable(points, func(a, b int) bool {
+		if points[a].Testcase != points[b].Testcase {
+			return points[a].Testcase < points[b].Testcase
+		}
+		return points[a].Workers < points[b].Workers
+	})
+	intervalGauges := []struct {
+		name, help string
+		get        func(p intervalPoint) float64
+	}{
+		{"gobench_interval_rate", "Requests per second within the interval.", func(p intervalPoint) float64 { return float64(p.Requests) / p.Interval.Seconds() }},
+		{"gobench_interval_errors", "Failed requests within the interval.", func(p intervalPoint) float64 { return float64(p.Errors) }},
+		{"gobench_interval_mean_latency_seconds", "Mean request latency within the interval.", func(p intervalPoint) float64 { return p.Mean.Seconds() }},
+		{"gobench_interval_p50_latency_seconds", "Estimated median request latency within the interval.", func(p intervalPoint) float64 { return p.P50.Seconds() }},
+		{"gobench_interval_p99_latency_seconds", "Estimated 99th percentile request latency within the interval.", func(p intervalPoint) float64 { return p.P99.Seconds() }},
+		{"gobench_interval_max_latency_seconds", "Largest request latency within the interval.", func(p intervalPoint) float64 { return p.Max.Seconds() }},
+	}
+	for _, g := range intervalGauges {
+		fmt.Fprintf(w, "# TYPE %s gauge\n# HELP %s %s\n", g.name, g.name, g.help)
+		for _, p := range points {
+			fmt.Fprintf(w, "%s%s %g %s\n", g.name,
+				openMetricsLabels(exportTags(p.Testcase, "", p.Workers), ""),
+				g.get(p), unixSeconds(p.Time))
+		}
+	}
+	fmt.Fprintln(w, "# EOF")
+}
+
+// writeExport writes the results to path in format, which is either
+// "influx" or "openmetrics".
+func writeExport(path string, format string) {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatalf("Failed to create export file: %v", err)
+	}
+	w := bufio.NewWriter(f)
+	resultsMutex.Lock()
+	if format == "influx" {
+		writeInflux(w)
+	} else {
+		writeOpenMetrics(w)
+	}
+	resultsMutex.Unlock()
+	if err = w.Flush(); err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		log.Fatalf("Failed to write export file: %v", err)
+	}
+	log.Printf("Wrote results to %s", path)
+}
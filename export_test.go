@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEscapeInflux(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"readDocs", "readDocs"},
+		{"read document ops", `read\ document\ ops`},
+		{"a,b=c", `a\,b\=c`},
+		{`back\slash`, `back\\slash`},
+	}
+	for _, tt := range tests {
+		if got := escapeInflux(tt.in); got != tt.want {
+			t.Errorf("escapeInflux(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestInfluxTags(t *testing.T) {
+	tests := []struct {
+		tags [][2]string
+		want string
+	}{
+		{nil, ""},
+		{[][2]string{{"testcase", "readDocs"}, {"name", ""}}, ",testcase=readDocs"},
+		{[][2]string{{"testcase", "mixed"}, {"name", "mixed read ops"}}, `,testcase=mixed,name=mixed\ read\ ops`},
+	}
+	for _, tt := range tests {
+		if got := influxTags(tt.tags); got != tt.want {
+			t.Errorf("influxTags(%v) = %q, want %q", tt.tags, got, tt.want)
+		}
+	}
+}
+
+func TestOpenMetricsLabels(t *testing.T) {
+	tests := []struct {
+		tags  [][2]string
+		extra string
+		want  string
+	}{
+		{[][2]string{{"testcase", "readDocs"}}, "", `{testcase="readDocs"}`},
+		{[][2]string{{"testcase", "readDocs"}, {"name", ""}}, `quantile="0.5"`, `{testcase="readDocs",name="",quantile="0.5"}`},
+		{[][2]string{{"name", `say "hi"`}}, "", `{name="say \"hi\""}`},
+	}
+	for _, tt := range tests {
+		if got := openMetricsLabels(tt.tags, tt.extra); got != tt.want {
+			t.Errorf("openMetricsLabels(%v, %q) = %q, want %q", tt.tags, tt.extra, got, tt.want)
+		}
+	}
+}
+
+func TestWriteInflux(t *testing.T) {
+	saved := results
+	defer func() { results = saved }()
+	results = []result{{
+		Testcase:    "readDocs",
+		Name:        "read document ops",
+		Time:        time.Unix(0, 42),
+		Parallelism: 4,
+		Samples:     10,
+		Mean:        1500 * time.Microsecond,
+	}}
+	var buf bytes.Buffer
+	writeInflux(&buf)
+	line := buf.String()
+	for _, want := range []string{
+		"gobench_summary,testcase=readDocs,name=read\\ document\\ ops,",
+		",parallelism=4,",
+		" samples=10i,mean_us=1500.000,",
+		" 42\n",
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("writeInflux wrote %q, which lacks %q", line, want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	driver "github.com/arangodb/go-driver"
+	"github.com/arangodb/go-driver/util"
+)
+
+// The foxx testcase installs a Foxx service into benchDB at -foxxMount,
+// from the zip bundle -foxxZip or, without one, the built-in service
+// below, and sends requests to its routes like the raw testcase does:
+// -rawMethod -foxxPath with -rawBody, or the templates of -rawFile, with
+// paths relative to the mount point. Afterwards the service is
+// uninstalled unless -cleanup=false. Since every request runs JavaScript
+// in one of the V8 contexts of the server, this shows how they limit the
+// throughput as -parallelism grows beyond --javascript.v8-contexts.
+
+var (
+	foxxZip   string = ""         // service bundle, "" for the built-in service
+	foxxMount string = "/gobench" // mount point in benchDB
+	foxxPath  string = "/hello"   // route of the service
+)
+
+// builtinFoxxService is a single file service with the routes
+//
+//	GET  /hello      a constant answer, the overhead of Foxx itself
+//	POST /echo       the JSON body
+//	GET  /doc/:key   document key of the test collection
+//	GET  /compute?n  n iterations of arithmetic in JavaScript
+const builtinFoxxService = `'use strict';
+const db = require('@arangodb').db;
+const router = require('@arangodb/foxx/router')();
+module.context.use(router);
+
+router.get('/hello', (req, res) => {
+  res.json({hello: 'world'});
+});
+router.post('/echo', (req, res) => {
+  res.json(req.json());
+});
+router.get('/doc/:key', (req, res) => {
+  res.json(db._collection('test').document(req.pathParams.key));
+});
+router.get('/compute', (req, res) => {
+  const n = Number(req.queryParams.n || 10000);
+  let x = 0;
+  for (let i = 0; i < n; i++) {
+    x += Math.sqrt(i);
+  }
+  res.json({x});
+});
+`
+
+// installFoxxService uploads the service bundle. This does not go through
+// the driver, which can only send JSON or VelocyPack bodies.
+func installFoxxService(db driver.Database) {
+	body, contentType := []byte(builtinFoxxService), "application/javascript"
+	if foxxZip != "" {
+		var err error
+		if body, err = ioutil.ReadFile(foxxZip); err != nil {
+			log.Fatalf("Failed to read Foxx service: %v", err)
+		}
+		contentType = "application/zip"
+	}
+	ep := util.FixupEndpointURLScheme(strings.Split(endpoint, ",")[0])
+	u := strings.TrimSuffix(ep, "/") + "/_db/" + db.Name() + "/_api/foxx?mount=" + url.QueryEscape(foxxMount)
+	req, err := http.NewRequest("POST", u, bytes.NewReader(body))
+	if err != nil {
+		log.Fatalf("Failed to install Foxx service: %v", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Fatalf("Failed to install Foxx service: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		log.Fatalf("Failed to install Foxx service: %s %s", resp.Status, msg)
+	}
+}
+
+func uninstallFoxxService(c driver.Client, db driver.Database) error {
+	return dbRequest(c, db, "DELETE", "_api/foxx/service",
+		map[string]string{"mount": foxxMount, "teardown": "true"}, nil, nil)
+}
+
+func foxxWorkload(c driver.Client, db driver.Database) workload {
+	if !strings.HasPrefix(foxxMount, "/") {
+		log.Fatalf("-foxxMount needs to start with /")
+	}
+	// A service left over from an earlier run is replaced.
+	uninstallFoxxService(c, db)
+	log.Printf("Installing Foxx service at %s...", foxxMount)
+	installFoxxService(db)
+
+	templates := readRawTemplates(foxxPath)
+	for i := range templates {
+		templates[i].Path = "/_db/" + db.Name() + foxxMount + templates[i].Path
+	}
+	w := templatesWorkload(c, "foxx", templates)
+	done := w.done
+	w.done = func(par int) {
+		done(par)
+		if cleanup {
+			if err := uninstallFoxxService(c, db); err != nil {
+				log.Fatalf("Failed to uninstall Foxx service: %v", err)
+			}
+		}
+	}
+	return w
+}
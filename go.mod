@@ -5,4 +5,5 @@ go 1.16
 require (
 	github.com/arangodb/go-driver v0.0.0-20210825071748-9f1169c6a7dc
 	golang.org/x/net v0.0.0-20200625001655-4c5254603344
+	gopkg.in/yaml.v3 v3.0.1
 )
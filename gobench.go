@@ -12,6 +12,7 @@ import (
 	//"net"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -33,21 +34,48 @@ var (
 	nrConnections int           = 1
 	endpoint      string        = "http://127.0.0.1:8529"
 	testcase      string        = "postDocs"
+	mix           string        = "" // workloads of the mixed testcase
 	replFactor    int           = 1
 	nrRequests    int           = 1000
 	parallelism   int           = 1
 	delay         time.Duration = 0
+	rate          float64       = 0 // requests per second, 0 means unlimited
+	duration      time.Duration = 0 // run for this long instead of nrRequests
 	cleanup       bool          = true
-	protocol      string        = "HTTP" // can be "VST" as well
+	protocol      string        = "HTTP"  // can be "VST" as well
+	contentType   string        = "vpack" // can be "json", for HTTP and HTTP2
 	usetls        bool          = false
 	username      string
 	password      string
 	outputFormat  string = "console" // can be "csv"
 
+	metricsAddr   string        = "" // serve live metrics here if set
+	metricsLinger time.Duration = 0  // keep serving metrics after the run
+
+	exportFile     string        = ""       // write results here if set
+	exportFormat   string        = "influx" // can be "openmetrics"
+	exportInterval time.Duration = time.Second
+
+	reportFile     string = "" // write an HTML report here if set
+	reportDataFile string = "" // save the report data as JSON here if set
+	reportFrom     string = "" // only render a report from these data files
+
+	scenarioFile string = "" // run the phases of this scenario file
+
 	submittedRequests int = 0 // the number of requests submitted
 )
 
-func logStats(name string, times []time.Duration) {
+// logStats reports the latencies of testcase tc, run by par workers,
+// under the given name and keeps a summary for the exports. Nothing is
+// reported for phases of a scenario which are not measured.
+func logStats(tc string, name string, par int, times []time.Duration) {
+	if !measuring {
+		return
+	}
+	if currentPhase != "" {
+		name = currentPhase + ": " + name
+	}
+	recordResult(tc, name, par, times)
 	if outputFormat == "console" {
 		logStatsConsole(name, times)
 	} else if outputFormat == "csv" {
@@ -67,7 +95,6 @@ func logStats(name string, times []time.Duration) {
 // - standard deviation
 //
 // all timings are in microseconds
-//
 func logStatsCSV(name string, times []time.Duration) {
 	nr := len(times)
 	if nr == 0 {
@@ -130,256 +157,232 @@ func logStatsConsole(name string, times []time.Duration) {
 	}
 }
 
-func doPostDocs(col driver.Collection) {
-	// Create documents
-	// Make nrRequests divisible by parallelism:
-	nrRequestsPerWorker := nrRequests / parallelism
-	nrRequests = nrRequestsPerWorker * parallelism
-	submittedRequests += nrRequests
-	times := make([]time.Duration, nrRequests, nrRequests)
-	wg := sync.WaitGroup{}
+// loadSettings control how runWorkers issues requests, they default to
+// the command line flags.
+type loadSettings struct {
+	nrRequests  int
+	parallelism int
+	delay       time.Duration
+	rate        float64
+	duration    time.Duration
+}
 
-	worker := func(innerTimes []time.Duration, initDelay time.Duration) {
-		time.Sleep(initDelay)
-		for i := 0; i < len(innerTimes); i++ {
-			startTime := time.Now()
-			book := Book{
-				Key:     "",
-				Title:   "Some small string",
-				NoPages: i,
-			}
-			_, err := col.CreateDocument(nil, book)
-			if err != nil {
-				log.Fatalf("Failed to create document: %v", err)
-			}
-			endTime := time.Now()
-			innerTimes[i] = endTime.Sub(startTime)
-			time.Sleep(delay)
-		}
-	}
+func currentLoad() loadSettings {
+	return loadSettings{nrRequests, parallelism, delay, rate, duration}
+}
 
-	for j := 0; j < parallelism; j++ {
-		wg.Add(1)
-		go func(jj int) {
-			defer wg.Done()
-			initTime := time.Duration(jj * int(delay) / parallelism)
-			// Give non-overlapping slices to the workers which together cover
-			// the whole of times:
-			worker(times[jj*nrRequestsPerWorker:(jj+1)*nrRequestsPerWorker],
-				initTime)
-		}(j)
-	}
+func (l loadSettings) apply() {
+	nrRequests, parallelism = l.nrRequests, l.parallelism
+	delay, rate, duration = l.delay, l.rate, l.duration
+}
 
-	wg.Wait()
-	logStats("create document ops", times)
+// evenParallelism returns the largest number of workers up to max which
+// divides n evenly, so that runWorkers issues all n requests.
+func evenParallelism(n int, max int) int {
+	par := max
+	for par > 1 && n%par != 0 {
+		par--
+	}
+	return par
 }
 
-func doSeedDocs(col driver.Collection) {
-	// Create documents with specific keys
+// runWorkers issues l.nrRequests calls of op, spread over l.parallelism
+// workers, and returns the time taken by every call. Each worker covers
+// the request numbers base..base+nrRequests/parallelism-1, op gets the
+// worker's base and the number i of the call within the worker. If
+// l.duration is set the workers instead keep going until it has passed
+// and i wraps around at the end of their range. With l.rate the workers
+// together start at most that many calls per second. Every call is
+// recorded in the live metrics under the testcase name tc, the first
+// error is fatal.
+func runWorkers(tc string, l loadSettings, op func(base, i int) error) []time.Duration {
 	// Make nrRequests divisible by parallelism:
-	nrRequestsPerWorker := nrRequests / parallelism
-	nrRequests = nrRequestsPerWorker * parallelism
-	submittedRequests += nrRequests
-	times := make([]time.Duration, nrRequests, nrRequests)
+	nrRequestsPerWorker := l.nrRequests / l.parallelism
+	if l.duration > 0 && nrRequestsPerWorker == 0 {
+		nrRequestsPerWorker = 1
+	}
+	var interval time.Duration // between the calls of one worker
+	if l.rate > 0 {
+		interval = time.Duration(float64(l.parallelism) / l.rate * float64(time.Second))
+	}
+	workerTimes := make([][]time.Duration, l.parallelism)
 	wg := sync.WaitGroup{}
 
-	worker := func(innerTimes []time.Duration, base int, initDelay time.Duration) {
+	worker := func(jj int, base int, initDelay time.Duration, deadline time.Time) {
+		var innerTimes []time.Duration
+		if l.duration == 0 {
+			innerTimes = make([]time.Duration, 0, nrRequestsPerWorker)
+		}
 		time.Sleep(initDelay)
-		for i := 0; i < len(innerTimes); i++ {
-			startTime := time.Now()
-			book := Book{
-				Key:     "K" + strconv.Itoa(base+i),
-				Title:   "Some small string",
-				NoPages: i,
+		next := time.Now()
+		for n := 0; ; n++ {
+			if l.duration > 0 {
+				if !time.Now().Before(deadline) {
+					break
+				}
+			} else if n >= nrRequestsPerWorker {
+				break
 			}
-			_, err := col.CreateDocument(nil, book)
-			if err != nil {
-				log.Fatalf("Failed to create document: %v", err)
+			if interval > 0 {
+				time.Sleep(time.Until(next))
+				next = next.Add(interval)
 			}
+			startTime := time.Now()
+			err := op(base, n%nrRequestsPerWorker)
 			endTime := time.Now()
-			innerTimes[i] = endTime.Sub(startTime)
-			time.Sleep(delay)
+			innerTimes = append(innerTimes, endTime.Sub(startTime))
+			observe(tc, innerTimes[n], err)
+			if err != nil {
+				log.Fatalf("Error in %s: %v", tc, err)
+			}
+			time.Sleep(l.delay)
 		}
+		workerTimes[jj] = innerTimes
 	}
 
-	for j := 0; j < parallelism; j++ {
+	deadline := time.Now().Add(l.duration)
+	setWorkers(tc, l.parallelism)
+	for j := 0; j < l.parallelism; j++ {
 		wg.Add(1)
 		go func(jj int) {
 			defer wg.Done()
-			initTime := time.Duration(jj * int(delay) / parallelism)
-			// Give non-overlapping slices to the workers which together cover
-			// the whole of times:
-			worker(times[jj*nrRequestsPerWorker:(jj+1)*nrRequestsPerWorker],
-				jj*nrRequestsPerWorker, initTime)
+			initTime := time.Duration(jj * int(l.delay) / l.parallelism)
+			if interval > 0 {
+				// Spread the workers evenly over the interval:
+				initTime += time.Duration(jj) * interval / time.Duration(l.parallelism)
+			}
+			// Give non-overlapping key ranges to the workers which together
+			// cover the whole of nrRequests:
+			worker(jj, jj*nrRequestsPerWorker, initTime, deadline)
 		}(j)
 	}
 
 	wg.Wait()
-	logStats("seed document ops", times)
-}
-
-func doReadDocs(col driver.Collection) {
-	// Read seeded documents with specific keys
-	// Make nrRequests divisible by parallelism:
-	nrRequestsPerWorker := nrRequests / parallelism
-	nrRequests = nrRequestsPerWorker * parallelism
-	submittedRequests += nrRequests
-	times := make([]time.Duration, nrRequests, nrRequests)
-	wg := sync.WaitGroup{}
-
-	worker := func(innerTimes []time.Duration, base int, initDelay time.Duration) {
-		time.Sleep(initDelay)
-		for i := 0; i < len(innerTimes); i++ {
-			startTime := time.Now()
-			var book Book
-			key := "K" + strconv.Itoa(base+i)
-			if _, err := col.ReadDocument(nil, key, &book); err != nil {
-				log.Fatalf("Failed to read document: %v", err)
-			}
-			endTime := time.Now()
-			innerTimes[i] = endTime.Sub(startTime)
-			time.Sleep(delay)
-		}
-	}
+	setWorkers(tc, 0)
 
-	for j := 0; j < parallelism; j++ {
-		wg.Add(1)
-		go func(jj int) {
-			defer wg.Done()
-			initTime := time.Duration(jj * int(delay) / parallelism)
-			// Give non-overlapping slices to the workers which together cover
-			// the whole of times:
-			worker(times[jj*nrRequestsPerWorker:(jj+1)*nrRequestsPerWorker],
-				jj*nrRequestsPerWorker, initTime)
-		}(j)
+	var times []time.Duration
+	for _, innerTimes := range workerTimes {
+		times = append(times, innerTimes...)
 	}
+	return times
+}
 
-	wg.Wait()
-	logStats("read document ops", times)
+// workload is a testcase which issues the same kind of request again
+// and again.
+type workload struct {
+	tc   string                  // testcase name, used in the metrics
+	name string                  // used in the statistics
+	op   func(base, i int) error // see runWorkers
+	done func(par int)           // called after a run by par workers if not nil
 }
 
-func doReadSameDocs(col driver.Collection) {
-	// Read always the same document
-	// Make nrRequests divisible by parallelism:
-	nrRequestsPerWorker := nrRequests / parallelism
-	nrRequests = nrRequestsPerWorker * parallelism
-	submittedRequests += nrRequests
-	times := make([]time.Duration, nrRequests, nrRequests)
-	wg := sync.WaitGroup{}
+// runWorkload runs w with the load settings of the command line.
+// keyedInserts are the testcases which insert documents with the key
+// "K" and the request number. With -duration the workers start over with
+// their request numbers, so these need an -overwriteMode which allows to
+// write existing keys.
+var keyedInserts = map[string]bool{"seedDocs": true, "seedDocsBatch": true}
 
-	worker := func(innerTimes []time.Duration, base int, initDelay time.Duration) {
-		time.Sleep(initDelay)
-		for i := 0; i < len(innerTimes); i++ {
-			startTime := time.Now()
-			var book Book
-			key := "K" + strconv.Itoa(base)
-			if _, err := col.ReadDocument(nil, key, &book); err != nil {
-				log.Fatalf("Failed to read document: %v", err)
-			}
-			endTime := time.Now()
-			innerTimes[i] = endTime.Sub(startTime)
-			time.Sleep(delay)
-		}
+// checkDuration fails unless testcase tc can run for duration with the
+// overwrite mode mode.
+func checkDuration(tc string, duration time.Duration, mode string) {
+	if duration > 0 && keyedInserts[tc] && (mode == "" || mode == "conflict") {
+		log.Fatalf("%s inserts every key once, with -duration it needs -overwriteMode replace, update or ignore", tc)
 	}
+}
 
-	for j := 0; j < parallelism; j++ {
-		wg.Add(1)
-		go func(jj int) {
-			defer wg.Done()
-			initTime := time.Duration(jj * int(delay) / parallelism)
-			// Give non-overlapping slices to the workers which together cover
-			// the whole of times:
-			worker(times[jj*nrRequestsPerWorker:(jj+1)*nrRequestsPerWorker],
-				jj*nrRequestsPerWorker, initTime)
-		}(j)
+func runWorkload(w workload) {
+	l := currentLoad()
+	checkDuration(w.tc, l.duration, overwriteMode)
+	times := runWorkers(w.tc, l, w.op)
+	submittedRequests += len(times)
+	logStats(w.tc, w.name, l.parallelism, times)
+	if w.done != nil {
+		w.done(l.parallelism)
 	}
-
-	wg.Wait()
-	logStats("read same document ops", times)
 }
 
-func doReplaceDocs(col driver.Collection) {
-	// Will replace the seeded documents.
-	// Make nrRequests divisible by parallelism:
-	nrRequestsPerWorker := nrRequests / parallelism
-	nrRequests = nrRequestsPerWorker * parallelism
-	submittedRequests += nrRequests
-	times := make([]time.Duration, nrRequests, nrRequests)
-	wg := sync.WaitGroup{}
+func postDocsWorkload(col driver.Collection) workload {
+	// Create documents
+	docs := newDocSource()
+	return workload{"postDocs", "create document ops", func(base, i int) error {
+		_, err := col.CreateDocument(insertContext(), docs.document(base, i, ""))
+		return err
+	}, nil}
+}
 
-	worker := func(innerTimes []time.Duration, base int, initDelay time.Duration) {
-		time.Sleep(initDelay)
-		for i := 0; i < len(innerTimes); i++ {
-			startTime := time.Now()
-			key := "K" + strconv.Itoa(base)
-			book := Book{
-				Key:     "K" + strconv.Itoa(base+i),
-				Title:   "Some small string",
-				NoPages: i,
-			}
-			if _, err := col.ReplaceDocument(nil, key, &book); err != nil {
-				log.Fatalf("Failed to replace document: %v", err)
-			}
-			endTime := time.Now()
-			innerTimes[i] = endTime.Sub(startTime)
-			time.Sleep(delay)
-		}
-	}
+func seedDocsWorkload(col driver.Collection) workload {
+	// Create documents with specific keys
+	docs := newDocSource()
+	return workload{"seedDocs", "seed document ops", func(base, i int) error {
+		_, err := col.CreateDocument(insertContext(), docs.document(base, i, "K"+strconv.Itoa(base+i)))
+		return err
+	}, nil}
+}
 
-	for j := 0; j < parallelism; j++ {
-		wg.Add(1)
-		go func(jj int) {
-			defer wg.Done()
-			initTime := time.Duration(jj * int(delay) / parallelism)
-			// Give non-overlapping slices to the workers which together cover
-			// the whole of times:
-			worker(times[jj*nrRequestsPerWorker:(jj+1)*nrRequestsPerWorker],
-				jj*nrRequestsPerWorker, initTime)
-		}(j)
-	}
+func readDocsWorkload(col driver.Collection) workload {
+	// Read seeded documents with specific keys
+	keys := newDocKeys(nrRequests)
+	return workload{"readDocs", "read document ops", func(base, i int) error {
+		var book Book
+		key := keys.key(base, base+i)
+		_, err := col.ReadDocument(nil, key, &book)
+		return err
+	}, nil}
+}
 
-	wg.Wait()
-	logStats("replace same document ops", times)
+func readSameDocsWorkload(col driver.Collection) workload {
+	// Read always the same document
+	return workload{"readSameDocs", "read same document ops", func(base, i int) error {
+		var book Book
+		key := "K" + strconv.Itoa(base)
+		_, err := col.ReadDocument(nil, key, &book)
+		return err
+	}, nil}
 }
 
-func doVersion(client driver.Client) {
-	// Create documents with specific keys
-	// Make nrRequests divisible by parallelism:
-	nrRequestsPerWorker := nrRequests / parallelism
-	nrRequests = nrRequestsPerWorker * parallelism
-	submittedRequests += nrRequests
-	times := make([]time.Duration, nrRequests, nrRequests)
-	wg := sync.WaitGroup{}
+func replaceDocsWorkload(col driver.Collection) workload {
+	// Will replace the seeded documents.
+	keys := newDocKeys(nrRequests)
+	docs := newDocSource()
+	opts := newWriteOptions(col, "replaceDocs", nrRequests)
+	return workload{"replaceDocs", "replace same document ops" + writeOptionsName(), func(base, i int) error {
+		key := keys.key(base, base)
+		meta, err := col.ReplaceDocument(opts.context(key), key, docs.document(base, i, "K"+strconv.Itoa(base+i)))
+		return opts.result(key, meta, err)
+	}, opts.done("replaceDocs")}
+}
 
-	worker := func(innerTimes []time.Duration, base int, initDelay time.Duration) {
-		time.Sleep(initDelay)
-		for i := 0; i < len(innerTimes); i++ {
-			startTime := time.Now()
-			// _, err := client.Version(driver.WithDetails(nil, true))
-			_, err := client.Version(driver.WithDetails(nil, false))
-			if err != nil {
-				log.Fatalf("Error in /_api/version call: %v", err)
-			}
-			endTime := time.Now()
-			innerTimes[i] = endTime.Sub(startTime)
-			time.Sleep(delay)
-		}
-	}
+func updateDocsWorkload(col driver.Collection) workload {
+	// Will update one attribute of each seeded document.
+	keys := newDocKeys(nrRequests)
+	opts := newWriteOptions(col, "updateDocs", nrRequests)
+	return workload{"updateDocs", "update document ops" + writeOptionsName(), func(base, i int) error {
+		key := keys.key(base, base+i)
+		meta, err := col.UpdateDocument(opts.context(key), key, map[string]interface{}{"no_pages": i})
+		return opts.result(key, meta, err)
+	}, opts.done("updateDocs")}
+}
 
-	for j := 0; j < parallelism; j++ {
-		wg.Add(1)
-		go func(jj int) {
-			defer wg.Done()
-			initTime := time.Duration(jj * int(delay) / parallelism)
-			// Give non-overlapping slices to the workers which together cover
-			// the whole of times:
-			worker(times[jj*nrRequestsPerWorker:(jj+1)*nrRequestsPerWorker],
-				jj*nrRequestsPerWorker, initTime)
-		}(j)
-	}
+func removeDocsWorkload(col driver.Collection) workload {
+	// Will remove the seeded documents.
+	keys := newDocKeys(nrRequests)
+	opts := newWriteOptions(col, "removeDocs", nrRequests)
+	return workload{"removeDocs", "remove document ops" + writeOptionsName(), func(base, i int) error {
+		key := keys.key(base, base+i)
+		meta, err := col.RemoveDocument(opts.context(key), key)
+		err = opts.result(key, meta, err)
+		opts.revs.Delete(key)
+		return err
+	}, opts.done("removeDocs")}
+}
 
-	wg.Wait()
-	logStats("/_api/version", times)
+func versionWorkload(client driver.Client) workload {
+	return workload{"version", "/_api/version", func(base, i int) error {
+		// _, err := client.Version(driver.WithDetails(nil, true))
+		_, err := client.Version(driver.WithDetails(nil, false))
+		return err
+	}, nil}
 }
 
 func doInitThreeDiamondAQL(client driver.Client) (driver.Database, driver.Collection) {
@@ -422,87 +425,302 @@ func doInitThreeDiamondAQL(client driver.Client) (driver.Database, driver.Collec
 	return db, col
 }
 
-func doReadThreeDiamondAQL(db driver.Database, col driver.Collection) {
+func readThreeDiamondAQLWorkload(db driver.Database, col driver.Collection) workload {
 	// Does a lot of three diamond AQL queries
-	// Make nrRequests divisible by parallelism:
-	nrRequestsPerWorker := nrRequests / parallelism
-	nrRequests = nrRequestsPerWorker * parallelism
-	submittedRequests += nrRequests
-	times := make([]time.Duration, nrRequests, nrRequests)
-	wg := sync.WaitGroup{}
-
-	worker := func(innerTimes []time.Duration, base int, initDelay time.Duration) {
-		time.Sleep(initDelay)
-		for i := 0; i < len(innerTimes); i++ {
-			startTime := time.Now()
-			var book Book
+	op := func(base, i int) error {
+		var book Book
 
-			// Get books by using AQL
-			cur, err := db.Query(nil, "FOR b1 IN books FOR b2 IN books FILTER b1._key == b2._key FOR b3 IN books FILTER b3._key == b1._key LIMIT 10 RETURN {_key: b1._key, title: b2.title, no_pages: b3.no_pages}", nil)
+		// Get books by using AQL
+		cur, err := db.Query(nil, "FOR b1 IN books FOR b2 IN books FILTER b1._key == b2._key FOR b3 IN books FILTER b3._key == b1._key LIMIT 10 RETURN {_key: b1._key, title: b2.title, no_pages: b3.no_pages}", nil)
+		if err != nil {
+			return err
+		}
+		for {
+			_, err = cur.ReadDocument(nil, &book)
 			if err != nil {
-				log.Fatalf("Failed to ask for query cursor: %v", err)
-			}
-			for {
-				_, err = cur.ReadDocument(nil, &book)
-				if err != nil {
-					if driver.IsNoMoreDocuments(err) {
-						break
-					}
-					log.Fatalf("Failed to read doc from cursor: %v", err)
+				if driver.IsNoMoreDocuments(err) {
+					return nil
 				}
+				return err
 			}
-
-			endTime := time.Now()
-			innerTimes[i] = endTime.Sub(startTime)
-			time.Sleep(delay)
 		}
 	}
-
-	for j := 0; j < parallelism; j++ {
-		wg.Add(1)
-		go func(jj int) {
-			defer wg.Done()
-			initTime := time.Duration(jj * int(delay) / parallelism)
-			// Give non-overlapping slices to the workers which together cover
-			// the whole of times:
-			worker(times[jj*nrRequestsPerWorker:(jj+1)*nrRequestsPerWorker],
-				jj*nrRequestsPerWorker, initTime)
-		}(j)
+	done := func(par int) {
+		if cleanup {
+			err := col.Remove(nil)
+			if err != nil {
+				log.Fatalf("Failed to drop collection: %v", err)
+			}
+			err = db.Remove(nil)
+			if err != nil {
+				log.Fatalf("Failed to drop database: %v", err)
+			}
+		}
 	}
+	return workload{"readThreeDiamondAQL", "read three diamond AQL ops", op, done}
+}
 
-	wg.Wait()
-	logStats("read three diamond AQL ops", times)
-	if cleanup {
-		err := col.Remove(nil)
-		if err != nil {
-			log.Fatalf("Failed to drop collection: %v", err)
-		}
-		err = db.Remove(nil)
-		if err != nil {
-			log.Fatalf("Failed to drop database: %v", err)
+// newWorkload prepares the workload of testcase tc, the second result is
+// false if there is no such workload.
+func newWorkload(c driver.Client, col driver.Collection, tc string) (workload, bool) {
+	switch tc {
+	case "postDocs":
+		return postDocsWorkload(col), true
+	case "seedDocs":
+		return seedDocsWorkload(col), true
+	case "readDocs":
+		return readDocsWorkload(col), true
+	case "readSameDocs":
+		return readSameDocsWorkload(col), true
+	case "replaceDocs":
+		return replaceDocsWorkload(col), true
+	case "updateDocs":
+		return updateDocsWorkload(col), true
+	case "removeDocs":
+		return removeDocsWorkload(col), true
+	case "streamTrx":
+		return streamTrxWorkload(col), true
+	case "jsTrx":
+		return jsTrxWorkload(col), true
+	case "aqlFunction":
+		return aqlFunctionWorkload(c, col), true
+	case "aql":
+		return aqlWorkload(col), true
+	case "cursor":
+		return cursorWorkload(col), true
+	case "indexInsert":
+		return indexInsertWorkload(c, col), true
+	case "indexLookup", "indexRange", "geoNear", "geoWithin":
+		return indexQueryWorkload(c, col, tc), true
+	case "postDocsBatch":
+		return postDocsBatchWorkload(col), true
+	case "seedDocsBatch":
+		return seedDocsBatchWorkload(col), true
+	case "readDocsBatch":
+		return readDocsBatchWorkload(col), true
+	case "replaceDocsBatch":
+		return replaceDocsBatchWorkload(col), true
+	case "importDocs":
+		return importDocsWorkload(c, col), true
+	case "contention":
+		return contentionWorkload(col), true
+	case "shardSingle", "shardAll":
+		return shardWorkload(c, col, tc), true
+	case "timeseries":
+		return timeseriesWorkload(col), true
+	case "tenantRead", "tenantReplace":
+		return tenantWorkload(c, col.Database(), tc), true
+	case "readThreeDiamondAQL":
+		AQLdb, AQLcol := doInitThreeDiamondAQL(c)
+		return readThreeDiamondAQLWorkload(AQLdb, AQLcol), true
+	case "version":
+		return versionWorkload(c), true
+	case "raw":
+		return rawWorkload(c), true
+	case "foxx":
+		return foxxWorkload(c, col.Database()), true
+	}
+	if _, ok := graphQueries[tc]; ok {
+		return graphWorkload(col.Database(), tc), true
+	}
+	if _, ok := searchQueries[tc]; ok {
+		return searchWorkload(c, col.Database(), tc), true
+	}
+	if strings.HasPrefix(tc, "ycsb") {
+		if _, ok := ycsbWorkloads[tc[4:]]; ok {
+			return ycsbWorkload(col, tc[4:]), true
 		}
 	}
+	return workload{}, false
+}
+
+// runTestcase runs the testcase tc against col and returns the time at
+// which the measurement started, that is after any preparations.
+func runTestcase(c driver.Client, col driver.Collection, tc string) time.Time {
+	switch tc {
+	case "all":
+		AQLdb, AQLcol := doInitThreeDiamondAQL(c)
+		startTime := time.Now()
+		runWorkload(postDocsWorkload(col))
+		runWorkload(seedDocsWorkload(col))
+		runWorkload(readDocsWorkload(col))
+		runWorkload(readSameDocsWorkload(col))
+		runWorkload(replaceDocsWorkload(col))
+		runWorkload(readThreeDiamondAQLWorkload(AQLdb, AQLcol))
+		return startTime
+	case "mixed":
+		return runMixed(c, col, parseMix(mix), currentLoad())
+	}
+	w, ok := newWorkload(c, col, tc)
+	if !ok {
+		log.Fatalf("Unknown testcase %s", tc)
+	}
+	startTime := time.Now()
+	runWorkload(w)
+	return startTime
 }
 
 func main() {
 	flag.IntVar(&nrConnections, "nrConnections", nrConnections, "number of connections")
 	flag.StringVar(&endpoint, "endpoint", endpoint, "server endpoint")
 	flag.StringVar(&testcase, "testcase", testcase, "test case")
+	flag.StringVar(&mix, "mix", mix, "workloads of testcase mixed, e.g. readDocs=80,replaceDocs=15,readThreeDiamondAQL=5")
 	flag.IntVar(&replFactor, "replicationFactor", replFactor, "replication factor of collection")
+	flag.BoolVar(&collectionWaitForSync, "collectionWaitForSync", collectionWaitForSync, "create collections with waitForSync")
+	flag.BoolVar(&waitForSync, "waitForSync", waitForSync, "send waitForSync with every write")
+	flag.IntVar(&writeConcern, "writeConcern", writeConcern, "write concern of collections, 0 for the server default")
+	flag.IntVar(&numberOfShards, "numberOfShards", numberOfShards, "number of shards of collections, 0 for the server default")
+	flag.StringVar(&overwriteMode, "overwriteMode", overwriteMode, "overwrite mode of inserts: replace, update, ignore or conflict")
+	flag.StringVar(&shardKeys, "shardKeys", shardKeys, "comma separated shard keys of collections, empty for _key")
+	flag.StringVar(&distributeShardsLike, "distributeShardsLike", distributeShardsLike, "collection whose shard distribution collections follow")
+	flag.BoolVar(&satellite, "satellite", satellite, "create satellite collections, Enterprise Edition only")
+	flag.StringVar(&smartJoinAttribute, "smartJoinAttribute", smartJoinAttribute, "smart join attribute of collections, Enterprise Edition only")
+	flag.BoolVar(&smart, "smart", smart, "create smart collections, needs -smartGraphAttribute, Enterprise Edition only")
+	flag.StringVar(&smartGraphAttribute, "smartGraphAttribute", smartGraphAttribute, "smart graph attribute of smart collections")
+	flag.IntVar(&shardDocs, "shardDocs", shardDocs, "documents of the shardSingle and shardAll testcases")
 	flag.IntVar(&nrRequests, "nrRequests", nrRequests, "number of requests")
+	flag.IntVar(&ycsbRecordCount, "ycsbRecordCount", ycsbRecordCount, "number of records of the YCSB workloads")
+	flag.IntVar(&ycsbFieldCount, "ycsbFieldCount", ycsbFieldCount, "number of fields of a YCSB record")
+	flag.IntVar(&ycsbFieldLength, "ycsbFieldLength", ycsbFieldLength, "length of a YCSB field")
+	flag.StringVar(&keyDistribution, "keyDistribution", keyDistribution, "key access distribution: sequential, uniform, zipfian, hotspot or latest")
+	flag.IntVar(&keySpace, "keySpace", keySpace, "number of seeded keys to choose from, 0 for nrRequests")
+	flag.Float64Var(&zipfianTheta, "zipfianTheta", zipfianTheta, "skew of the zipfian and latest key distributions, between 0 and 1")
+	flag.Float64Var(&hotspotSetFraction, "hotspotSetFraction", hotspotSetFraction, "fraction of the keys which are hot for the hotspot distribution")
+	flag.Float64Var(&hotspotOpFraction, "hotspotOpFraction", hotspotOpFraction, "fraction of the requests going to hot keys for the hotspot distribution")
+	flag.Int64Var(&seed, "seed", seed, "seed of the random generators, 0 to seed from the time")
+	flag.IntVar(&docSize, "docSize", docSize, "approximate size of written documents in bytes, 0 for small fixed documents")
+	flag.StringVar(&docSizeDistribution, "docSizeDistribution", docSizeDistribution, "document size distribution: fixed, uniform or lognormal")
+	flag.IntVar(&docSizeMax, "docSizeMax", docSizeMax, "maximal document size for the uniform distribution")
+	flag.Float64Var(&docSizeSigma, "docSizeSigma", docSizeSigma, "sigma of the lognormal document size distribution")
+	flag.IntVar(&docAttributes, "docAttributes", docAttributes, "number of attributes per object of generated documents")
+	flag.IntVar(&docDepth, "docDepth", docDepth, "nesting depth of generated documents")
+	flag.IntVar(&docArrayLength, "docArrayLength", docArrayLength, "length of array attributes of generated documents, 0 for none")
+	flag.Float64Var(&docEntropy, "docEntropy", docEntropy, "fraction of random characters in strings of generated documents, the rest compresses well")
+	flag.IntVar(&batchSize, "batchSize", batchSize, "documents per request of the batch testcases")
+	flag.StringVar(&importFile, "importFile", importFile, "file of JSON lines or JSON array for importDocs, generated documents if empty")
+	flag.StringVar(&importFormat, "importFormat", importFormat, "import request format: jsonl or array")
+	flag.IntVar(&importChunkSize, "importChunkSize", importChunkSize, "documents per import request")
+	flag.StringVar(&importOnDuplicate, "importOnDuplicate", importOnDuplicate, "import policy for existing keys: error, update, replace or ignore")
+	flag.BoolVar(&returnOld, "returnOld", returnOld, "ask for the old document in replaceDocs, updateDocs and removeDocs")
+	flag.BoolVar(&returnNew, "returnNew", returnNew, "ask for the new document in replaceDocs and updateDocs")
+	flag.BoolVar(&ifMatch, "ifMatch", ifMatch, "send the last seen revision with replaceDocs, updateDocs and removeDocs")
+	flag.BoolVar(&silent, "silent", silent, "ask for empty responses in replaceDocs, updateDocs and removeDocs")
+	flag.IntVar(&trxSize, "trxSize", trxSize, "operations per stream transaction")
+	flag.Float64Var(&trxWriteFraction, "trxWriteFraction", trxWriteFraction, "fraction of the operations of a stream transaction which are updates")
+	flag.Float64Var(&trxAbortFraction, "trxAbortFraction", trxAbortFraction, "fraction of the stream transactions which are aborted instead of committed")
+	flag.IntVar(&trxKeys, "trxKeys", trxKeys, "number of seeded keys the stream transactions choose from, fewer keys mean more conflicts")
+	flag.StringVar(&jsTrxFile, "jsTrxFile", jsTrxFile, "file with the JavaScript action of jsTrx, a built-in one if empty")
+	flag.StringVar(&aqlFunctionFile, "aqlFunctionFile", aqlFunctionFile, "file with the code of the AQL user function GOBENCH::FN, a built-in one if empty")
+	flag.StringVar(&aqlFunctionQuery, "aqlFunctionQuery", aqlFunctionQuery, "query of the aqlFunction testcase")
+	flag.StringVar(&aqlFiles, "aqlFiles", aqlFiles, "comma separated files with one AQL query each for the aql testcase")
+	flag.StringVar(&aqlBindVars, "aqlBindVars", aqlBindVars, "comma separated bind parameters name=generator, generators: key, range:a:b, list:x|y|z or a value")
+	flag.IntVar(&aqlBatchSize, "aqlBatchSize", aqlBatchSize, "cursor batch size of the aql testcase, 0 for the server default")
+	flag.DurationVar(&aqlTTL, "aqlTTL", aqlTTL, "cursor time to live of the aql testcase, 0 for the server default")
+	flag.Int64Var(&aqlMemoryLimit, "aqlMemoryLimit", aqlMemoryLimit, "memory limit in bytes of the queries of the aql testcase, 0 for none")
+	flag.BoolVar(&aqlFullCount, "aqlFullCount", aqlFullCount, "ask for the full count in the aql testcase")
+	flag.IntVar(&aqlProfile, "aqlProfile", aqlProfile, "profiling level of the queries of the aql testcase, 0 for none")
+	flag.StringVar(&cursorQuery, "cursorQuery", cursorQuery, "query of the cursor testcase, @n is set to -cursorResults")
+	flag.IntVar(&cursorResults, "cursorResults", cursorResults, "value of @n in the query of the cursor testcase")
+	flag.IntVar(&cursorBatchSize, "cursorBatchSize", cursorBatchSize, "batch size of the cursor testcase")
+	flag.BoolVar(&cursorStream, "cursorStream", cursorStream, "use a streaming cursor in the cursor testcase")
+	flag.StringVar(&cursorLoop, "cursorLoop", cursorLoop, "how the cursor testcase reads results: noMoreDocuments or hasMore")
+	flag.IntVar(&graphVertices, "graphVertices", graphVertices, "number of vertices of the generated graph")
+	flag.StringVar(&graphShape, "graphShape", graphShape, "shape of the generated graph: random, social or tree")
+	flag.IntVar(&graphDegree, "graphDegree", graphDegree, "edges per vertex of the generated graph, children per vertex for trees")
+	flag.IntVar(&graphDepth, "graphDepth", graphDepth, "depth of graphTraversal")
+	flag.StringVar(&graphDirection, "graphDirection", graphDirection, "direction of the graph testcases: OUTBOUND, INBOUND or ANY")
+	flag.IntVar(&graphPaths, "graphPaths", graphPaths, "number of paths of graphKShortestPaths")
+	flag.StringVar(&indexType, "indexType", indexType, "type of the indexes of the index testcases: persistent, hash, ttl, geo or inverted")
+	flag.IntVar(&indexes, "indexes", indexes, "number of indexes for indexInsert")
+	flag.IntVar(&indexRange, "indexRange", indexRange, "values per query of indexRange")
+	flag.Float64Var(&geoRadius, "geoRadius", geoRadius, "radius in meters of geoWithin")
+	flag.IntVar(&geoLimit, "geoLimit", geoLimit, "documents per query of geoNear")
+	flag.DurationVar(&ttlExpireAfter, "ttlExpireAfter", ttlExpireAfter, "lifetime of the documents of indexInsert with TTL indexes")
+	flag.IntVar(&searchDocs, "searchDocs", searchDocs, "documents in the corpus of the search testcases")
+	flag.IntVar(&searchWords, "searchWords", searchWords, "words per document of the search corpus")
+	flag.IntVar(&searchVocabulary, "searchVocabulary", searchVocabulary, "different words in the search corpus")
+	flag.StringVar(&searchView, "searchView", searchView, "view type of the search testcases: arangosearch or search-alias")
+	flag.StringVar(&searchAnalyzer, "searchAnalyzer", searchAnalyzer, "analyzer of the search testcases, gobench for -searchAnalyzerDefinition")
+	flag.StringVar(&searchAnalyzerDefinition, "searchAnalyzerDefinition", searchAnalyzerDefinition, "JSON definition of the gobench analyzer")
+	flag.IntVar(&searchLimit, "searchLimit", searchLimit, "results per search query")
+	flag.DurationVar(&searchCommitInterval, "searchCommitInterval", searchCommitInterval, "commit interval of the search view, 0 for the server default")
+	flag.DurationVar(&searchPollInterval, "searchPollInterval", searchPollInterval, "time between searches for the new document in searchLag")
+	flag.IntVar(&contentionKeys, "contentionKeys", contentionKeys, "shared documents incremented by the contention testcase")
+	flag.StringVar(&contentionMode, "contentionMode", contentionMode, "how the contention testcase increments: ifMatch or aql")
+	flag.IntVar(&tenants, "tenants", tenants, "number of tenants of the tenant testcases")
+	flag.StringVar(&tenantMode, "tenantMode", tenantMode, "what a tenant gets: collections or databases")
+	flag.IntVar(&tenantDocs, "tenantDocs", tenantDocs, "documents per tenant")
+	flag.StringVar(&tenantDistribution, "tenantDistribution", tenantDistribution, "how requests are spread over the tenants: uniform, zipfian or hotspot")
+	flag.IntVar(&tsSensors, "tsSensors", tsSensors, "number of sensors of the timeseries testcase")
+	flag.IntVar(&tsBatchSize, "tsBatchSize", tsBatchSize, "points per insert of the timeseries testcase")
+	flag.Float64Var(&tsQueryFraction, "tsQueryFraction", tsQueryFraction, "fraction of the timeseries requests which are queries")
+	flag.StringVar(&tsQuery, "tsQuery", tsQuery, "timeseries query: sensor for one sensor, all for all sensors")
+	flag.DurationVar(&tsWindow, "tsWindow", tsWindow, "time range of the timeseries queries")
+	flag.StringVar(&tsIndex, "tsIndex", tsIndex, "timeseries index: persistent, or ttl for a TTL index in addition")
+	flag.DurationVar(&tsRetention, "tsRetention", tsRetention, "expiry of timeseries points with -tsIndex=ttl")
+	flag.DurationVar(&tsInterval, "tsInterval", tsInterval, "interval for which timeseries query latencies are reported")
+	flag.StringVar(&rawMethod, "rawMethod", rawMethod, "HTTP method of the raw testcase")
+	flag.StringVar(&rawPath, "rawPath", rawPath, "path of the raw testcase")
+	flag.StringVar(&rawBody, "rawBody", rawBody, "JSON body of the raw testcase")
+	flag.StringVar(&rawStatus, "rawStatus", rawStatus, "comma separated status codes the raw testcase accepts")
+	flag.StringVar(&rawFile, "rawFile", rawFile, "file with the request templates of the raw testcase, one JSON object per line")
+	flag.StringVar(&foxxZip, "foxxZip", foxxZip, "zip bundle of the Foxx service of the foxx testcase, empty for the built-in service")
+	flag.StringVar(&foxxMount, "foxxMount", foxxMount, "mount point of the Foxx service in benchDB")
+	flag.StringVar(&foxxPath, "foxxPath", foxxPath, "route of the Foxx service requested by the foxx testcase")
 	flag.IntVar(&parallelism, "parallelism", parallelism, "parallelism")
 	flag.DurationVar(&delay, "delay", delay, "delay per thread between operations")
+	flag.Float64Var(&rate, "rate", rate, "maximal number of requests per second of all threads together, 0 for unlimited")
+	flag.DurationVar(&duration, "duration", duration, "run each testcase for this long instead of for nrRequests requests")
 	flag.BoolVar(&cleanup, "cleanup", cleanup, "flag whether to perform cleanup")
 	flag.StringVar(&protocol, "protocol", protocol, "protocol: HTTP or VST or HTTP2")
+	flag.StringVar(&contentType, "contentType", contentType, "content type of HTTP and HTTP2 requests: vpack or json")
 	flag.BoolVar(&usetls, "useTLS", usetls, "flag whether to use TLS")
 	flag.StringVar(&username, "auth.user", username, "Authentication Username")
 	flag.StringVar(&password, "auth.pass", password, "Authentication Password")
 	flag.StringVar(&outputFormat, "outputFormat", outputFormat, "output format: console or csv")
+	flag.StringVar(&metricsAddr, "metricsAddr", metricsAddr, "address to serve Prometheus metrics on, e.g. :9100")
+	flag.DurationVar(&metricsLinger, "metricsLinger", metricsLinger, "time to keep serving metrics after the run")
+	flag.StringVar(&exportFile, "exportFile", exportFile, "file to export the results to")
+	flag.StringVar(&exportFormat, "exportFormat", exportFormat, "export format: influx or openmetrics")
+	flag.DurationVar(&exportInterval, "exportInterval", exportInterval, "length of the intervals of the exported time series")
+	flag.StringVar(&reportFile, "report", reportFile, "file to write an HTML report to")
+	flag.StringVar(&reportDataFile, "reportData", reportDataFile, "file to save the report data of this run to, as JSON")
+	flag.StringVar(&scenarioFile, "scenario", scenarioFile, "YAML or JSON scenario file describing the phases to run")
+	flag.StringVar(&reportFrom, "reportFrom", reportFrom, "comma separated report data files to render into -report without running a benchmark")
 	flag.Parse()
 
 	if outputFormat != "console" && outputFormat != "csv" {
 		log.Fatalf("-outputFormat needs to be console or csv")
 	}
+	if exportFormat != "influx" && exportFormat != "openmetrics" {
+		log.Fatalf("-exportFormat needs to be influx or openmetrics")
+	}
+	switch keyDistribution {
+	case "sequential", "uniform", "zipfian", "hotspot", "latest":
+	default:
+		log.Fatalf("-keyDistribution needs to be sequential, uniform, zipfian, hotspot or latest")
+	}
+	checkDurabilityFlags()
+	checkShardingFlags()
+	if batchSize < 1 {
+		log.Fatalf("-batchSize needs to be at least 1")
+	}
+	if zipfianTheta <= 0 || zipfianTheta >= 1 {
+		log.Fatalf("-zipfianTheta needs to be between 0 and 1")
+	}
+
+	if reportFrom != "" {
+		if reportFile == "" {
+			log.Fatalf("-reportFrom needs -report")
+		}
+		writeReport(reportFile, readRunData(reportFrom))
+		return
+	}
+
+	var sc *scenario
+	if scenarioFile != "" {
+		sc = loadScenario(scenarioFile)
+	}
 
 	// If we log to CSV we suppress Logger output and use fmt to print.
 	if outputFormat == "csv" {
@@ -512,13 +730,26 @@ func main() {
 	log.Printf("Server endpoint: %s using %d connections", endpoint, nrConnections)
 	log.Println()
 
+	if metricsAddr != "" {
+		serveMetrics(metricsAddr)
+	}
+
+	endpoints := strings.Split(endpoint, ",")
+	ct := driver.ContentTypeVelocypack
+	switch contentType {
+	case "vpack":
+	case "json":
+		ct = driver.ContentTypeJSON
+	default:
+		log.Fatalf("-contentType needs to be vpack or json")
+	}
 	var conn driver.Connection
 	var err error
 	if protocol == "HTTP" {
 		connConfig := http.ConnectionConfig{
-			Endpoints: []string{endpoint},
-			ContentType: driver.ContentTypeVelocypack,
-			ConnLimit: nrConnections,
+			Endpoints:   endpoints,
+			ContentType: ct,
+			ConnLimit:   nrConnections,
 		}
 		if usetls {
 			connConfig.TLSConfig = &tls.Config{
@@ -531,7 +762,7 @@ func main() {
 		}
 	} else if protocol == "VST" {
 		connConfig := vst.ConnectionConfig{
-			Endpoints: []string{endpoint},
+			Endpoints: endpoints,
 			Transport: vstproto.TransportConfig{
 				ConnLimit: nrConnections,
 			},
@@ -549,20 +780,20 @@ func main() {
 		var connConfig http.ConnectionConfig
 		if usetls {
 			connConfig = http.ConnectionConfig{
-				Endpoints: []string{endpoint},
-				ContentType: driver.ContentTypeVelocypack,
-				ConnLimit: nrConnections,
+				Endpoints:   endpoints,
+				ContentType: ct,
+				ConnLimit:   nrConnections,
 				Transport: &http2.Transport{
 					TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 				},
 			}
 		} else {
 			connConfig = http.ConnectionConfig{
-				Endpoints: []string{endpoint},
-				ContentType: driver.ContentTypeVelocypack,
-				ConnLimit: nrConnections,
+				Endpoints:   endpoints,
+				ContentType: ct,
+				ConnLimit:   nrConnections,
 				Transport: &http2.Transport{
-					AllowHTTP:       true,
+					AllowHTTP: true,
 					DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
 						return net.Dial(network, addr)
 					},
@@ -599,45 +830,47 @@ func main() {
 	// Create collection
 	col, err := db.Collection(nil, "test")
 	if err != nil {
-		opts := driver.CreateCollectionOptions{
-			ReplicationFactor: replFactor,
-		}
+		opts := collectionOptions()
 		col, err = db.CreateCollection(nil, "test", &opts)
 		if err != nil {
 			log.Fatalf("Failed to create collection: %v", err)
 		}
+	} else {
+		checkCollectionOptions(col)
+	}
+
+	var stopSampler func()
+	if exportFile != "" || reportFile != "" || reportDataFile != "" {
+		if v, err := c.Version(nil); err == nil {
+			serverVersion = string(v.Version)
+		}
+		stopSampler = startSampler(exportInterval)
 	}
 
 	startTime := time.Now()
-	switch testcase {
-	case "postDocs":
-		doPostDocs(col)
-	case "seedDocs":
-		doSeedDocs(col)
-	case "readDocs":
-		doReadDocs(col)
-	case "readSameDocs":
-		doReadSameDocs(col)
-	case "replaceDocs":
-		doReplaceDocs(col)
-	case "readThreeDiamondAQL":
-		AQLdb, AQLcol := doInitThreeDiamondAQL(c)
-		startTime = time.Now()
-		doReadThreeDiamondAQL(AQLdb, AQLcol)
-	case "all":
-		AQLdb, AQLcol := doInitThreeDiamondAQL(c)
-		startTime = time.Now()
-		doPostDocs(col)
-		doSeedDocs(col)
-		doReadDocs(col)
-		doReadSameDocs(col)
-		doReplaceDocs(col)
-		doReadThreeDiamondAQL(AQLdb, AQLcol)
-	case "version":
-		doVersion(c)
+	if sc != nil {
+		sc.run(c, db, col)
+	} else {
+		startTime = runTestcase(c, col, testcase)
 	}
 	endTime := time.Now()
 
+	if stopSampler != nil {
+		stopSampler()
+	}
+	if exportFile != "" {
+		writeExport(exportFile, exportFormat)
+	}
+	if reportFile != "" || reportDataFile != "" {
+		run := collectRunData(startTime)
+		if reportDataFile != "" {
+			writeRunData(reportDataFile, run)
+		}
+		if reportFile != "" {
+			writeReport(reportFile, []runData{run})
+		}
+	}
+
 	log.Println()
 	log.Printf("Time for %d requests: %v", submittedRequests, endTime.Sub(startTime))
 	log.Printf("Reqs/s: %d", int(float64(submittedRequests)/(float64(endTime.Sub(startTime))/1000000000.0)))
@@ -652,4 +885,8 @@ func main() {
 			log.Fatalf("Failed to drop database: %v", err)
 		}
 	}
+	if metricsAddr != "" && metricsLinger > 0 {
+		log.Printf("Serving metrics for another %v", metricsLinger)
+		time.Sleep(metricsLinger)
+	}
 }
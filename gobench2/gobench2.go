@@ -2,6 +2,7 @@ package main
 
 import (
 	"crypto/tls"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -12,10 +13,13 @@ import (
 	"runtime"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/arangodb/go-driver/v2/arangodb"
+	"github.com/arangodb/go-driver/v2/arangodb/shared"
 	"github.com/arangodb/go-driver/v2/connection"
 	"golang.org/x/net/http2"
 )
@@ -42,6 +46,21 @@ var (
 	password      string
 	outputFormat  string = "console" // can be "csv"
 
+	returnOld bool = false // ask for the old document in writes
+	returnNew bool = false // ask for the new document in writes
+	ifMatch   bool = false // only write the last seen revision
+	silent    bool = false // ask for empty responses to writes
+
+	cursorQuery     string = `FOR i IN 1..@n RETURN {i, s: CONCAT("value", i)}`
+	cursorResults   int    = 100000 // value of @n
+	cursorBatchSize int    = 1000   // documents per batch
+	cursorStream    bool   = false  // use a streaming cursor
+
+	rawMethod string = "GET"
+	rawPath   string = "/_api/version"
+	rawBody   string = ""    // JSON
+	rawStatus string = "200" // comma separated
+
 	submittedRequests int = 0 // the number of requests submitted
 )
 
@@ -268,8 +287,95 @@ func doReadSameDocs(col arangodb.Collection) {
 	logStats("read same document ops", times)
 }
 
+// writeOptionsName lists the write options which are set, for the names
+// of the statistics.
+func writeOptionsName() string {
+	var opts []string
+	for _, o := range []struct {
+		set  bool
+		name string
+	}{{returnOld, "returnOld"}, {returnNew, "returnNew"}, {ifMatch, "ifMatch"}, {silent, "silent"}} {
+		if o.set {
+			opts = append(opts, o.name)
+		}
+	}
+	if len(opts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(opts, ", ") + ")"
+}
+
+func newBool(b bool) *bool {
+	return &b
+}
+
+// revisions remembers the last seen revision of every key for -ifMatch.
+// The revisions of the seeded documents are read before the writes are
+// measured, documents which did not exist then are written without one.
+// Writes of missing documents and with outdated revisions are counted
+// instead of failing the run.
+type revisions struct {
+	col                arangodb.Collection
+	revs               sync.Map
+	missing            int64 // writes of documents which did not exist
+	preconditionFailed int64 // writes of documents changed by someone else
+}
+
+// newRevisions reads the revisions of the documents "K0" to "K<n-1>" of
+// col with -ifMatch.
+func newRevisions(col arangodb.Collection, n int) *revisions {
+	r := &revisions{col: col}
+	if ifMatch {
+		for k := 0; k < n; k++ {
+			var book Book
+			if meta, err := col.ReadDocument(nil, "K"+strconv.Itoa(k), &book); err == nil {
+				r.revs.Store(meta.Key, meta.Rev)
+			}
+		}
+	}
+	return r
+}
+
+func (r *revisions) get(key string) string {
+	if rev, ok := r.revs.Load(key); ok {
+		return rev.(string)
+	}
+	return ""
+}
+
+// result records the outcome of a write of key which returned rev.
+func (r *revisions) result(key string, rev string, err error) error {
+	switch {
+	case shared.IsNotFound(err):
+		atomic.AddInt64(&r.missing, 1)
+		r.revs.Delete(key)
+		return nil
+	case shared.IsPreconditionFailed(err):
+		atomic.AddInt64(&r.preconditionFailed, 1)
+		r.revs.Delete(key)
+		return nil
+	case err != nil:
+		return err
+	}
+	if ifMatch {
+		r.revs.Store(key, rev)
+	}
+	return nil
+}
+
+// log reports the counted failures under name.
+func (r *revisions) log(name string) {
+	if r.missing > 0 {
+		log.Printf("%s: %d documents did not exist", name, r.missing)
+	}
+	if r.preconditionFailed > 0 {
+		log.Printf("%s: %d revisions did not match", name, r.preconditionFailed)
+	}
+}
+
 func doReplaceDocs(col arangodb.Collection) {
 	// Will replace the seeded documents.
+	revs := newRevisions(col, nrRequests)
 	// Make nrRequests divisible by parallelism:
 	nrRequestsPerWorker := nrRequests / parallelism
 	nrRequests = nrRequestsPerWorker * parallelism
@@ -287,7 +393,21 @@ func doReplaceDocs(col arangodb.Collection) {
 				Title:   "Some small string",
 				NoPages: i,
 			}
-			if _, err := col.UpdateDocument(nil, key, &book); err != nil {
+			rev := revs.get(key)
+			var old, doc Book
+			opts := arangodb.CollectionDocumentReplaceOptions{
+				IfMatch: rev,
+				Silent:  newBool(silent),
+			}
+			// Setting OldObject and NewObject asks for them:
+			if returnOld {
+				opts.OldObject = &old
+			}
+			if returnNew {
+				opts.NewObject = &doc
+			}
+			resp, err := col.ReplaceDocumentWithOptions(nil, key, &book, &opts)
+			if err = revs.result(key, resp.Rev, err); err != nil {
 				log.Fatalf("Failed to replace document: %+v", err)
 			}
 			endTime := time.Now()
@@ -309,7 +429,99 @@ func doReplaceDocs(col arangodb.Collection) {
 	}
 
 	wg.Wait()
-	logStats("replace same document ops", times)
+	logStats("replace same document ops"+writeOptionsName(), times)
+	revs.log("replace same document ops"+writeOptionsName())
+}
+
+func doUpdateDocs(col arangodb.Collection) {
+	// Will update one attribute of each seeded document.
+	revs := newRevisions(col, nrRequests)
+	_, times := call(nrRequests, parallelism)(func(id int) error {
+		key := "K" + strconv.Itoa(id)
+		rev := revs.get(key)
+		var old, doc Book
+		opts := arangodb.CollectionDocumentUpdateOptions{
+			IfMatch: rev,
+			Silent:  newBool(silent),
+		}
+		if returnOld {
+			opts.OldObject = &old
+		}
+		if returnNew {
+			opts.NewObject = &doc
+		}
+		resp, err := col.UpdateDocumentWithOptions(nil, key, map[string]interface{}{"no_pages": id}, &opts)
+		return revs.result(key, resp.Rev, err)
+	})
+	submittedRequests += len(times)
+	logStats("update document ops"+writeOptionsName(), times)
+	revs.log("update document ops"+writeOptionsName())
+}
+
+func doRemoveDocs(col arangodb.Collection) {
+	// Will remove the seeded documents.
+	revs := newRevisions(col, nrRequests)
+	_, times := call(nrRequests, parallelism)(func(id int) error {
+		key := "K" + strconv.Itoa(id)
+		rev := revs.get(key)
+		var old Book
+		opts := arangodb.CollectionDocumentDeleteOptions{
+			IfMatch: rev,
+			Silent:  newBool(silent),
+		}
+		if returnOld {
+			opts.OldObject = &old
+		}
+		_, err := col.DeleteDocumentWithOptions(nil, key, &opts)
+		err = revs.result(key, "", err)
+		revs.revs.Delete(key)
+		return err
+	})
+	submittedRequests += len(times)
+	logStats("remove document ops"+writeOptionsName(), times)
+	revs.log("remove document ops"+writeOptionsName())
+}
+
+func doCursor(db arangodb.Database) {
+	// Retrieves large query results with the HasMore loop of driver v2,
+	// to compare with the cursor testcase of gobench.
+	opts := arangodb.QueryOptions{
+		BatchSize: cursorBatchSize,
+		BindVars:  map[string]interface{}{"n": cursorResults},
+	}
+	opts.Options.Stream = cursorStream
+	var mutex sync.Mutex
+	var firstBatch, nextBatch []time.Duration
+	_, times := call(nrRequests, parallelism)(func(id int) error {
+		startTime := time.Now()
+		cur, err := db.Query(nil, cursorQuery, &opts)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+		first := time.Now().Sub(startTime)
+		var next []time.Duration
+		for n := 0; cur.HasMore(); n++ {
+			var v interface{}
+			readStart := time.Now()
+			if _, err = cur.ReadDocument(nil, &v); err != nil {
+				return err
+			}
+			if n > 0 && n%cursorBatchSize == 0 {
+				// This read had to fetch the next batch.
+				next = append(next, time.Now().Sub(readStart))
+			}
+		}
+		mutex.Lock()
+		firstBatch = append(firstBatch, first)
+		nextBatch = append(nextBatch, next...)
+		mutex.Unlock()
+		return nil
+	})
+	submittedRequests += len(times)
+	logStats("cursor query ops (hasMore)", times)
+	logStats("cursor first batch ops", firstBatch)
+	logStats("cursor next batch ops", nextBatch)
 }
 
 func doVersionRaw(conn connection.Connection, client arangodb.Client) {
@@ -321,6 +533,40 @@ func doVersionRaw(conn connection.Connection, client arangodb.Client) {
 	logStats("RAW /_api/version", times)
 }
 
+// doRaw sends -rawMethod -rawPath with the body -rawBody directly through
+// the connection and checks that the status is one of -rawStatus. {{i}}
+// in the path and body is replaced by the number of the request.
+func doRaw(conn connection.Connection) {
+	var status []int
+	for _, s := range strings.Split(rawStatus, ",") {
+		code, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			log.Fatalf("Invalid -rawStatus %s", rawStatus)
+		}
+		status = append(status, code)
+	}
+	_, times := call(nrRequests, parallelism)(func(id int) error {
+		path := strings.Replace(rawPath, "{{i}}", strconv.Itoa(id), -1)
+		var modifiers []connection.RequestModifier
+		if rawBody != "" {
+			body := json.RawMessage(strings.Replace(rawBody, "{{i}}", strconv.Itoa(id), -1))
+			modifiers = append(modifiers, connection.WithBody(body))
+		}
+		resp, err := connection.Call(nil, conn, rawMethod, path, nil, modifiers...)
+		if err != nil {
+			return err
+		}
+		for _, code := range status {
+			if resp.Code() == code {
+				return nil
+			}
+		}
+		return fmt.Errorf("unexpected status code %d", resp.Code())
+	})
+	submittedRequests += len(times)
+	logStats("RAW "+rawMethod+" "+rawPath, times)
+}
+
 func doVersion(conn connection.Connection, client arangodb.Client) {
 	_, times := call(nrRequests, parallelism)(func(id int) error {
 		_, err := client.Version(nil)
@@ -483,11 +729,26 @@ func main() {
 	flag.StringVar(&username, "auth.user", username, "Authentication Username")
 	flag.StringVar(&password, "auth.pass", password, "Authentication Password")
 	flag.StringVar(&outputFormat, "outputFormat", outputFormat, "output format: console or csv")
+	flag.BoolVar(&returnOld, "returnOld", returnOld, "ask for the old document in replaceDocs, updateDocs and removeDocs")
+	flag.BoolVar(&returnNew, "returnNew", returnNew, "ask for the new document in replaceDocs and updateDocs")
+	flag.BoolVar(&ifMatch, "ifMatch", ifMatch, "send the last seen revision with replaceDocs, updateDocs and removeDocs")
+	flag.BoolVar(&silent, "silent", silent, "ask for empty responses in replaceDocs, updateDocs and removeDocs")
+	flag.StringVar(&cursorQuery, "cursorQuery", cursorQuery, "query of the cursor testcase, @n is set to -cursorResults")
+	flag.IntVar(&cursorResults, "cursorResults", cursorResults, "value of @n in the query of the cursor testcase")
+	flag.IntVar(&cursorBatchSize, "cursorBatchSize", cursorBatchSize, "batch size of the cursor testcase")
+	flag.BoolVar(&cursorStream, "cursorStream", cursorStream, "use a streaming cursor in the cursor testcase")
+	flag.StringVar(&rawMethod, "rawMethod", rawMethod, "HTTP method of the raw testcase")
+	flag.StringVar(&rawPath, "rawPath", rawPath, "path of the raw testcase")
+	flag.StringVar(&rawBody, "rawBody", rawBody, "JSON body of the raw testcase")
+	flag.StringVar(&rawStatus, "rawStatus", rawStatus, "comma separated status codes the raw testcase accepts")
 	flag.Parse()
 
 	if outputFormat != "console" && outputFormat != "csv" {
 		log.Fatalf("-outputFormat needs to be console or csv")
 	}
+	if ifMatch && silent {
+		log.Fatalf("-ifMatch needs the revisions of the responses and does not work with -silent")
+	}
 
 	// If we log to CSV we suppress Logger output and use fmt to print.
 	if outputFormat == "csv" {
@@ -541,6 +802,10 @@ func main() {
 		doReadSameDocs(col)
 	case "replaceDocs":
 		doReplaceDocs(col)
+	case "updateDocs":
+		doUpdateDocs(col)
+	case "removeDocs":
+		doRemoveDocs(col)
 	case "readThreeDiamondAQL":
 		AQLdb, AQLcol := doInitThreeDiamondAQL(c)
 		startTime = time.Now()
@@ -554,9 +819,13 @@ func main() {
 		doReadSameDocs(col)
 		doReplaceDocs(col)
 		doReadThreeDiamondAQL(AQLdb, AQLcol)
+	case "cursor":
+		doCursor(db)
 	case "version":
 		doVersion(conn, c)
 		doVersionRaw(conn, c)
+	case "raw":
+		doRaw(conn)
 	}
 	endTime := time.Now()
 
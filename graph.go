@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	driver "github.com/arangodb/go-driver"
+)
+
+// The graph testcases query the named graph gobenchGraph of the vertex
+// collection "vertices" and the edge collection "edges" in benchDB. The
+// graph is generated before the first run with -graphVertices vertices
+// "V0", "V1", ... in one of these shapes:
+//
+//	random  every vertex has edges to 0 to 2*-graphDegree random vertices
+//	social  preferential attachment: every new vertex has edges to
+//	        -graphDegree earlier ones, preferring those with many edges,
+//	        which gives the power law degrees of social networks
+//	tree    a tree with -graphDegree children per vertex, edges pointing
+//	        away from the root V0
+//
+// It is kept for further runs with -cleanup=false. The start vertices,
+// and the targets of path searches, are chosen by -keyDistribution,
+// uniformly without one. The testcases are
+//
+//	graphNeighbors       the direct neighbors of a vertex
+//	graphTraversal       all vertices up to -graphDepth steps away
+//	graphShortestPath    the shortest path between two vertices
+//	graphKShortestPaths  the -graphPaths shortest paths between two vertices
+//
+// and follow edges in -graphDirection.
+
+const (
+	graphName          = "gobenchGraph"
+	graphVertexCol     = "vertices"
+	graphEdgeCol       = "edges"
+	graphLoadBatchSize = 1000
+	graphInfoAttribute = "generated"
+)
+
+var (
+	graphVertices  int    = 1000       // number of vertices
+	graphShape     string = "random"   // random, social or tree
+	graphDegree    int    = 5          // edges per vertex, children in trees
+	graphDepth     int    = 2          // steps of graphTraversal
+	graphDirection string = "OUTBOUND" // OUTBOUND, INBOUND or ANY
+	graphPaths     int    = 3          // paths of graphKShortestPaths
+)
+
+// graphEdge is a generated edge.
+type graphEdge struct {
+	From string `json:"_from"`
+	To   string `json:"_to"`
+}
+
+func vertexID(n int) string {
+	return graphVertexCol + "/V" + strconv.Itoa(n)
+}
+
+// generateEdges returns the edges of the configured graph shape.
+func generateEdges(r *rand.Rand) []graphEdge {
+	var edges []graphEdge
+	switch graphShape {
+	case "random":
+		for v := 0; v < graphVertices; v++ {
+			for d := r.Intn(2*graphDegree + 1); d > 0; d-- {
+				edges = append(edges, graphEdge{vertexID(v), vertexID(r.Intn(graphVertices))})
+			}
+		}
+	case "social":
+		// Every vertex appears in ends once per edge it has, so that a
+		// random element of it is chosen proportionally to the degree.
+		var ends []int
+		for v := 1; v < graphVertices; v++ {
+			targets := map[int]bool{}
+			for len(targets) < graphDegree && len(targets) < v {
+				var t int
+				// Now and then a uniformly chosen one, so that new
+				// vertices get edges as well:
+				if len(ends) == 0 || r.Intn(graphDegree+1) == 0 {
+					t = r.Intn(v)
+				} else {
+					t = ends[r.Intn(len(ends))]
+				}
+				targets[t] = true
+			}
+			for t := range targets {
+				edges = append(edges, graphEdge{vertexID(v), vertexID(t)})
+				ends = append(ends, v, t)
+			}
+		}
+	case "tree":
+		for v := 1; v < graphVertices; v++ {
+			edges = append(edges, graphEdge{vertexID((v - 1) / graphDegree), vertexID(v)})
+		}
+	}
+	return edges
+}
+
+// graphInfo describes the generated graph, it is stored in vertex V0 to
+// tell whether an existing graph can be used.
+func graphInfo() string {
+	return graphShape + " " + strconv.Itoa(graphVertices) + " " + strconv.Itoa(graphDegree)
+}
+
+// loadGraph creates and fills the graph unless it exists already with
+// the same settings.
+func loadGraph(db driver.Database) {
+	exists, err := db.GraphExists(nil, graphName)
+	if err != nil {
+		log.Fatalf("Failed to look for graph: %v", err)
+	}
+	if exists {
+		var root map[string]interface{}
+		vertices, err := db.Collection(nil, graphVertexCol)
+		if err == nil {
+			_, err = vertices.ReadDocument(nil, "V0", &root)
+		}
+		if err == nil && root[graphInfoAttribute] == graphInfo() {
+			return
+		}
+		log.Printf("Replacing graph %s", graphName)
+		g, err := db.Graph(nil, graphName)
+		if err == nil {
+			err = g.Remove(nil)
+		}
+		if err != nil {
+			log.Fatalf("Failed to remove graph: %v", err)
+		}
+	}
+	for _, name := range []string{graphVertexCol, graphEdgeCol} {
+		if col, err := db.Collection(nil, name); err == nil {
+			if err = col.Remove(nil); err != nil {
+				log.Fatalf("Failed to drop collection: %v", err)
+			}
+		}
+	}
+	opts := collectionOptions()
+	_, err = db.CreateGraph(nil, graphName, &driver.CreateGraphOptions{
+		EdgeDefinitions: []driver.EdgeDefinition{{
+			Collection: graphEdgeCol,
+			From:       []string{graphVertexCol},
+			To:         []string{graphVertexCol},
+		}},
+		NumberOfShards:    opts.NumberOfShards,
+		ReplicationFactor: opts.ReplicationFactor,
+		WriteConcern:      opts.WriteConcern,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create graph: %v", err)
+	}
+	vertices, err := db.Collection(nil, graphVertexCol)
+	if err != nil {
+		log.Fatalf("Failed to open collection: %v", err)
+	}
+	edges, err := db.Collection(nil, graphEdgeCol)
+	if err != nil {
+		log.Fatalf("Failed to open collection: %v", err)
+	}
+
+	s := seed
+	if s == 0 {
+		s = time.Now().UnixNano()
+	}
+	generated := generateEdges(rand.New(rand.NewSource(s)))
+	log.Printf("Loading %s graph with %d vertices and %d edges...", graphShape, graphVertices, len(generated))
+	for start := 0; start < graphVertices; start += graphLoadBatchSize {
+		var batch []map[string]interface{}
+		for v := start; v < graphVertices && v < start+graphLoadBatchSize; v++ {
+			batch = append(batch, map[string]interface{}{"_key": "V" + strconv.Itoa(v)})
+		}
+		if start == 0 {
+			batch[0][graphInfoAttribute] = graphInfo()
+		}
+		if _, errs, err := vertices.CreateDocuments(nil, batch); batchError(errs, err) != nil {
+			log.Fatalf("Failed to create vertices: %v", batchError(errs, err))
+		}
+	}
+	for start := 0; start < len(generated); start += graphLoadBatchSize {
+		end := start + graphLoadBatchSize
+		if end > len(generated) {
+			end = len(generated)
+		}
+		if _, errs, err := edges.CreateDocuments(nil, generated[start:end]); batchError(errs, err) != nil {
+			log.Fatalf("Failed to create edges: %v", batchError(errs, err))
+		}
+	}
+}
+
+// drainQuery runs a query and reads all of its results.
+func drainQuery(ctx context.Context, db driver.Database, query string, bindVars map[string]interface{}) error {
+	cur, err := db.Query(ctx, query, bindVars)
+	if err != nil {
+		return err
+	}
+	defer cur.Close()
+	for {
+		var v interface{}
+		if _, err = cur.ReadDocument(nil, &v); err != nil {
+			if driver.IsNoMoreDocuments(err) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+var graphQueries = map[string]struct {
+	name  string
+	query string
+}{
+	"graphNeighbors": {"graph neighbors ops",
+		"FOR v IN 1..1 %s @start GRAPH @graph RETURN v._key"},
+	"graphTraversal": {"graph traversal ops",
+		"FOR v IN 1..@depth %s @start GRAPH @graph RETURN v._key"},
+	"graphShortestPath": {"graph shortest path ops",
+		"FOR v IN %s SHORTEST_PATH @start TO @target GRAPH @graph RETURN v._key"},
+	"graphKShortestPaths": {"graph k shortest paths ops",
+		"FOR p IN %s K_SHORTEST_PATHS @start TO @target GRAPH @graph LIMIT @paths RETURN p.vertices[*]._key"},
+}
+
+func graphWorkload(db driver.Database, tc string) workload {
+	direction := strings.ToUpper(graphDirection)
+	switch direction {
+	case "OUTBOUND", "INBOUND", "ANY":
+	default:
+		log.Fatalf("-graphDirection needs to be OUTBOUND, INBOUND or ANY")
+	}
+	switch graphShape {
+	case "random", "social", "tree":
+	default:
+		log.Fatalf("-graphShape needs to be random, social or tree")
+	}
+	if graphVertices < 1 || graphDegree < 1 {
+		log.Fatalf("-graphVertices and -graphDegree need to be at least 1")
+	}
+	loadGraph(db)
+
+	q := graphQueries[tc]
+	query := strings.Replace(q.query, "%s", direction, 1)
+	dist := keyDistribution
+	if dist == "sequential" {
+		dist = "uniform"
+	}
+	keys := newKeyChooser(dist, graphVertices, nil)
+	var rands workerRands
+
+	return workload{tc, q.name, func(base, i int) error {
+		r := rands.get(base)
+		bindVars := map[string]interface{}{
+			"start": vertexID(keys.next(r)),
+			"graph": graphName,
+		}
+		if strings.Contains(query, "@target") {
+			bindVars["target"] = vertexID(keys.next(r))
+		}
+		if strings.Contains(query, "@depth") {
+			bindVars["depth"] = graphDepth
+		}
+		if strings.Contains(query, "@paths") {
+			bindVars["paths"] = graphPaths
+		}
+		return drainQuery(nil, db, query, bindVars)
+	}, nil}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	driver "github.com/arangodb/go-driver"
+)
+
+// The importDocs testcase loads documents through /_api/import, each
+// request carrying a chunk of -importChunkSize documents in the format
+// -importFormat, "jsonl" (one document per line) or "array" (a single
+// array). The documents are generated like for seedDocs, with the keys
+// "K0", "K1", ... and -nrRequests is the number of chunks. With
+// -importFile they come from a file of JSON lines or a JSON array
+// instead, which is cut into chunks which the requests take in turn,
+// starting over after the last one; with -nrRequests set to the number
+// of chunks, which is logged, every document is imported once. The
+// importers are the -parallelism workers. Besides the latencies per
+// chunk the created, updated, ignored and failed documents are reported
+// together with the documents per second and the JSON megabytes per
+// second. The JSON of file chunks is measured before the run, that of
+// generated chunks is known from the shape of the documents, so that
+// neither is encoded for it.
+
+var (
+	importFile        string = ""      // file to import, generated documents if empty
+	importFormat      string = "jsonl" // can be "array"
+	importChunkSize   int    = 1000    // documents per import request
+	importOnDuplicate string = "error" // can be "update", "replace" or "ignore"
+)
+
+// readImportFile reads the documents of a file of JSON lines or of a
+// JSON array.
+func readImportFile(name string) []interface{} {
+	f, err := os.Open(name)
+	if err != nil {
+		log.Fatalf("Failed to open import file: %v", err)
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	first, err := r.Peek(1)
+	for err == nil && (first[0] == ' ' || first[0] == '\t' || first[0] == '\r' || first[0] == '\n') {
+		r.ReadByte()
+		first, err = r.Peek(1)
+	}
+	var docs []interface{}
+	dec := json.NewDecoder(r)
+	if err == nil && first[0] == '[' {
+		err = dec.Decode(&docs)
+	} else {
+		for err == nil {
+			var doc interface{}
+			if err = dec.Decode(&doc); err == nil {
+				docs = append(docs, doc)
+			}
+		}
+		if err == io.EOF {
+			err = nil
+		}
+	}
+	if err != nil {
+		log.Fatalf("Failed to read import file %s: %v", name, err)
+	}
+	return docs
+}
+
+// importStats adds up the results of the import requests.
+type importStats struct {
+	once                                     sync.Once
+	start                                    time.Time
+	created, updated, ignored, errors, bytes int64
+}
+
+func (s *importStats) add(st driver.ImportDocumentStatistics, bytes int) {
+	atomic.AddInt64(&s.created, st.Created)
+	atomic.AddInt64(&s.updated, st.Updated)
+	atomic.AddInt64(&s.ignored, st.Ignored)
+	atomic.AddInt64(&s.errors, st.Errors)
+	atomic.AddInt64(&s.bytes, int64(bytes))
+}
+
+// importChunk sends one import request with the given documents.
+func importChunk(c driver.Client, col driver.Collection, docs []interface{}) (driver.ImportDocumentStatistics, error) {
+	if importFormat == "jsonl" {
+		opts := driver.ImportDocumentOptions{OnDuplicate: driver.ImportOnDuplicate(importOnDuplicate)}
+		return col.ImportDocuments(writeContext(), docs, &opts)
+	}
+	var st driver.ImportDocumentStatistics
+	conn := c.Connection()
+	req, err := conn.NewRequest("POST", path.Join("_db", col.Database().Name(), "_api/import"))
+	if err != nil {
+		return st, err
+	}
+	req.SetQuery("collection", col.Name())
+	req.SetQuery("type", "array")
+	req.SetQuery("onDuplicate", importOnDuplicate)
+	if waitForSync {
+		req.SetQuery("waitForSync", "true")
+	}
+	if _, err = req.SetBody(docs); err != nil {
+		return st, err
+	}
+	resp, err := conn.Do(nil, req)
+	if err != nil {
+		return st, err
+	}
+	if err = resp.CheckStatus(201); err != nil {
+		return st, err
+	}
+	err = resp.ParseBody("", &st)
+	return st, err
+}
+
+// jsonBody returns the JSON which an import of docs in importFormat
+// sends, the request body unless the connection uses VelocyPack.
+func jsonBody(docs []interface{}) []byte {
+	if importFormat == "array" {
+		data, _ := json.Marshal(docs)
+		return data
+	}
+	var buf bytes.Buffer
+	for _, doc := range docs {
+		data, _ := json.Marshal(doc)
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// formatSize is what importFormat adds to the JSON of n documents, the
+// newlines of jsonl or the brackets and commas of an array.
+func formatSize(n int) int {
+	if importFormat == "array" {
+		return n + 1
+	}
+	return n
+}
+
+func importDocsWorkload(c driver.Client, col driver.Collection) workload {
+	switch importFormat {
+	case "jsonl", "array":
+	default:
+		log.Fatalf("-importFormat needs to be jsonl or array")
+	}
+	switch importOnDuplicate {
+	case "error", "update", "replace", "ignore":
+	default:
+		log.Fatalf("-importOnDuplicate needs to be error, update, replace or ignore")
+	}
+
+	var chunks [][]interface{}
+	var sizes []int // JSON bytes of the chunks
+	if importFile != "" {
+		docs := readImportFile(importFile)
+		if len(docs) == 0 {
+			log.Fatalf("Import file %s has no documents", importFile)
+		}
+		for start := 0; start < len(docs); start += importChunkSize {
+			end := start + importChunkSize
+			if end > len(docs) {
+				end = len(docs)
+			}
+			chunks = append(chunks, docs[start:end])
+			sizes = append(sizes, len(jsonBody(docs[start:end])))
+		}
+		log.Printf("Importing %d documents from %s in %d chunks, -nrRequests=%d imports each once",
+			len(docs), importFile, len(chunks), len(chunks))
+	}
+
+	docs := newDocSource()
+	stats := &importStats{}
+	next := int64(0) // next file chunk
+	op := func(base, i int) error {
+		var chunk []interface{}
+		var size int
+		if chunks != nil {
+			k := int(atomic.AddInt64(&next, 1)-1) % len(chunks)
+			chunk, size = chunks[k], sizes[k]
+		} else {
+			chunk = make([]interface{}, importChunkSize)
+			for j := range chunk {
+				n := (base+i)*importChunkSize + j
+				var docSize int
+				chunk[j], docSize = docs.documentSize(base, n, "K"+strconv.Itoa(n))
+				size += docSize
+			}
+			size += formatSize(len(chunk))
+		}
+		stats.once.Do(func() { stats.start = time.Now() })
+		st, err := importChunk(c, col, chunk)
+		if err != nil {
+			return err
+		}
+		stats.add(st, size)
+		return nil
+	}
+	done := func(par int) {
+		elapsed := time.Since(stats.start)
+		if !measuring || elapsed <= 0 {
+			return
+		}
+		imported := stats.created + stats.updated + stats.ignored + stats.errors
+		log.Printf("Import: %d created, %d updated, %d ignored, %d errors",
+			stats.created, stats.updated, stats.ignored, stats.errors)
+		log.Printf("Import: %d documents in %v, %.0f documents/s, %.2f MB/s",
+			imported, elapsed, float64(imported)/elapsed.Seconds(),
+			float64(stats.bytes)/1e6/elapsed.Seconds())
+	}
+	return workload{"importDocs", "import chunk ops", op, done}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"strconv"
+	"time"
+
+	driver "github.com/arangodb/go-driver"
+)
+
+// Index testcases. indexInsert inserts documents into the test
+// collection after making sure it has exactly -indexes secondary indexes
+// of -indexType, so that running it with 0, 1, 2, ... indexes shows the
+// cost of maintaining them. The documents have the attributes a0, a1, ...
+// which are indexed, one per index, a location and, for TTL indexes, an
+// expiry time -ttlExpireAfter in the future, so that the server keeps
+// removing documents while new ones come in. Document n has the value n
+// in all of its attributes a0, a1, ...
+//
+// indexLookup and indexRange query documents written by indexInsert by
+// a0, with an index of -indexType, for one value or -indexRange values.
+// geoNear and geoWithin query the locations with a geo index, for the
+// -geoLimit nearest documents or those within -geoRadius meters of the
+// location of a random document. Values and documents are chosen by
+// -keyDistribution, uniformly without one, out of -keySpace or
+// -nrRequests.
+
+var (
+	indexType      string        = "persistent"     // persistent, hash, ttl, geo or inverted
+	indexes        int           = 1                // indexes for indexInsert
+	indexRange     int           = 100              // values per indexRange query
+	geoRadius      float64       = 100000           // meters for geoWithin
+	geoLimit       int           = 10               // documents for geoNear
+	ttlExpireAfter time.Duration = 10 * time.Second // lifetime of documents with TTL indexes
+)
+
+const geoIndexName = "gobenchGeo"
+
+// gobenchIndexName matches the names of the indexes of indexInsert.
+var gobenchIndexName = regexp.MustCompile(`^gobench[0-9]+$`)
+
+// indexDefinition is the body to create index number j of type typ.
+func indexDefinition(typ string, j int) map[string]interface{} {
+	def := map[string]interface{}{
+		"type":   typ,
+		"name":   "gobench" + strconv.Itoa(j),
+		"fields": []string{"a" + strconv.Itoa(j)},
+	}
+	switch typ {
+	case "ttl":
+		def["fields"] = []string{"expires"}
+		def["expireAfter"] = 0
+	case "geo":
+		def["fields"] = []string{"location"}
+		def["geoJson"] = true
+	}
+	return def
+}
+
+// ensureIndexes makes sure that col has the indexes gobench0 to
+// gobench<n-1> of type typ, and no other ones of that name pattern. Index
+// definitions go through the HTTP API, since the driver does not know
+// all index types.
+func ensureIndexes(c driver.Client, col driver.Collection, typ string, n int) {
+	switch typ {
+	case "persistent", "hash", "inverted":
+	case "ttl", "geo":
+		if n > 1 {
+			log.Fatalf("A collection can only have one %s index on the same attribute", typ)
+		}
+	default:
+		log.Fatalf("-indexType needs to be persistent, hash, ttl, geo or inverted")
+	}
+	db := col.Database()
+	var list struct {
+		Indexes []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"indexes"`
+	}
+	err := dbRequest(c, db, "GET", "_api/index", map[string]string{"collection": col.Name()}, nil, &list)
+	if err != nil {
+		log.Fatalf("Failed to list indexes: %v", err)
+	}
+	// The server reports hash indexes as persistent ones.
+	want := typ
+	if want == "hash" {
+		want = "persistent"
+	}
+	have := map[string]bool{}
+	for _, idx := range list.Indexes {
+		if !gobenchIndexName.MatchString(idx.Name) {
+			continue
+		}
+		j, _ := strconv.Atoi(idx.Name[len("gobench"):])
+		if j < n && idx.Type == want {
+			have[idx.Name] = true
+			continue
+		}
+		if err = dbRequest(c, db, "DELETE", "_api/index/"+idx.ID, nil, nil, nil); err != nil {
+			log.Fatalf("Failed to drop index: %v", err)
+		}
+	}
+	for j := 0; j < n; j++ {
+		def := indexDefinition(typ, j)
+		if have[def["name"].(string)] {
+			continue
+		}
+		log.Printf("Creating %s index %s", typ, def["name"])
+		err = dbRequest(c, db, "POST", "_api/index", map[string]string{"collection": col.Name()}, def, nil)
+		if err != nil {
+			log.Fatalf("Failed to create index: %v", err)
+		}
+	}
+}
+
+// indexDocument returns document n for the index testcases.
+func indexDocument(n int) map[string]interface{} {
+	doc := map[string]interface{}{"location": indexLocation(n)}
+	for j := 0; j < indexes || j == 0; j++ {
+		doc["a"+strconv.Itoa(j)] = n
+	}
+	if indexType == "ttl" {
+		doc["expires"] = time.Now().Add(ttlExpireAfter).Unix()
+	}
+	return doc
+}
+
+// indexLocation is the location of document n, [longitude, latitude]
+// spread evenly over the globe.
+func indexLocation(n int) []float64 {
+	h := fnvHash(n)
+	return []float64{
+		float64(h%36000000)/1e5 - 180,
+		float64((h>>32)%18000000)/1e5 - 90,
+	}
+}
+
+func indexInsertWorkload(c driver.Client, col driver.Collection) workload {
+	ensureIndexes(c, col, indexType, indexes)
+	name := "insert with " + strconv.Itoa(indexes) + " " + indexType + " indexes ops"
+	done := func(par int) {
+		if indexType == "ttl" && measuring {
+			count, err := col.Count(nil)
+			if err != nil {
+				log.Fatalf("Failed to count documents: %v", err)
+			}
+			log.Printf("%d documents left after the TTL expiry", count)
+		}
+	}
+	return workload{"indexInsert", name, func(base, i int) error {
+		_, err := col.CreateDocument(insertContext(), indexDocument(base+i))
+		return err
+	}, done}
+}
+
+// indexQueryWorkload prepares one of the index query testcases.
+func indexQueryWorkload(c driver.Client, col driver.Collection, tc string) workload {
+	var query, name string
+	switch tc {
+	case "indexLookup", "indexRange":
+		if indexType == "ttl" || indexType == "geo" {
+			log.Fatalf("%s needs -indexType persistent, hash or inverted", tc)
+		}
+		ensureIndexes(c, col, indexType, 1)
+		hint := ""
+		if indexType == "inverted" {
+			hint = ` OPTIONS {indexHint: "gobench0", forceIndexHint: true}`
+		}
+		if tc == "indexLookup" {
+			query = "FOR d IN @@col" + hint + " FILTER d.a0 == @value RETURN d"
+			name = indexType + " index lookup ops"
+		} else {
+			query = "FOR d IN @@col" + hint + " FILTER d.a0 >= @value AND d.a0 < @value + @range RETURN d"
+			name = indexType + " index range ops"
+		}
+	case "geoNear":
+		ensureGeoIndex(col)
+		query = "FOR d IN @@col SORT GEO_DISTANCE(@location, d.location) LIMIT @limit RETURN d"
+		name = "geo near ops"
+	case "geoWithin":
+		ensureGeoIndex(col)
+		query = "FOR d IN @@col FILTER GEO_DISTANCE(@location, d.location) <= @radius RETURN d"
+		name = "geo within ops"
+	}
+
+	db := col.Database()
+	n := keySpace
+	if n <= 0 {
+		n = nrRequests
+	}
+	dist := keyDistribution
+	if dist == "sequential" {
+		dist = "uniform"
+	}
+	keys := newKeyChooser(dist, n, nil)
+	var rands workerRands
+
+	return workload{tc, name, func(base, i int) error {
+		v := keys.next(rands.get(base))
+		bindVars := map[string]interface{}{"@col": col.Name()}
+		switch tc {
+		case "indexLookup":
+			bindVars["value"] = v
+		case "indexRange":
+			bindVars["value"], bindVars["range"] = v, indexRange
+		case "geoNear":
+			bindVars["location"], bindVars["limit"] = indexLocation(v), geoLimit
+		case "geoWithin":
+			bindVars["location"], bindVars["radius"] = indexLocation(v), geoRadius
+		}
+		return drainQuery(nil, db, query, bindVars)
+	}, nil}
+}
+
+// ensureGeoIndex makes sure that col has a geo index on the locations.
+func ensureGeoIndex(col driver.Collection) {
+	_, _, err := col.EnsureGeoIndex(nil, []string{"location"}, &driver.EnsureGeoIndexOptions{
+		GeoJSON: true,
+		Name:    geoIndexName,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create geo index: %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"hash/fnv"
+	"log"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Key access distributions decide which of the seeded documents "K0" to
+// "K<n-1>" a read or replace goes to:
+//
+//	sequential  every key once in order, K<base+i> (the default)
+//	uniform     all keys equally likely
+//	zipfian     a few popular keys, scattered over the key space
+//	hotspot     -hotspotOpFraction of the requests go to the first
+//	            -hotspotSetFraction of the keys
+//	latest      zipfian, the most recently written keys are the popular ones
+//
+// With -seed the random choices are repeatable: every worker draws from
+// its own generator seeded with seed plus its base.
+
+var (
+	keyDistribution    string  = "sequential" // sequential, uniform, zipfian, hotspot or latest
+	keySpace           int     = 0            // number of keys to choose from, 0 for nrRequests
+	zipfianTheta       float64 = 0.99         // skew of the zipfian and latest distributions
+	hotspotSetFraction float64 = 0.2          // fraction of the keys which are hot
+	hotspotOpFraction  float64 = 0.8          // fraction of the requests going to hot keys
+	seed               int64   = 0            // seed of the random generators, 0 for the time
+)
+
+// keyChooser picks the number of the key to access next.
+type keyChooser interface {
+	next(r *rand.Rand) int
+}
+
+type uniformKeys struct {
+	n int
+}
+
+func (u uniformKeys) next(r *rand.Rand) int {
+	return r.Intn(u.n)
+}
+
+// zipfian draws numbers from 0 to n-1 such that small numbers are the
+// most likely, with the algorithm of Gray et al. "Quickly generating
+// billion-record synthetic databases" which YCSB uses as well.
+type zipfian struct {
+	n                   int
+	theta, alpha, zetan float64
+	eta                 float64
+}
+
+func zeta(n int, theta float64) float64 {
+	var sum float64
+	for i := 1; i <= n; i++ {
+		sum += 1 / math.Pow(float64(i), theta)
+	}
+	return sum
+}
+
+func newZipfian(n int, theta float64) *zipfian {
+	z := &zipfian{n: n, theta: theta, alpha: 1 / (1 - theta), zetan: zeta(n, theta)}
+	z.eta = (1 - math.Pow(2/float64(n), 1-theta)) / (1 - zeta(2, theta)/z.zetan)
+	return z
+}
+
+func (z *zipfian) next(r *rand.Rand) int {
+	u := r.Float64()
+	uz := u * z.zetan
+	if uz < 1 {
+		return 0
+	}
+	if uz < 1+math.Pow(0.5, z.theta) {
+		return 1
+	}
+	v := int(float64(z.n) * math.Pow(z.eta*u-z.eta+1, z.alpha))
+	if v >= z.n {
+		v = z.n - 1
+	}
+	return v
+}
+
+// scrambledZipfian spreads the popular numbers of a zipfian over the
+// whole key space, so that they do not all end up in the same blocks.
+type scrambledZipfian struct {
+	z *zipfian
+}
+
+func (s scrambledZipfian) next(r *rand.Rand) int {
+	return int(fnvHash(s.z.next(r)) % uint64(s.z.n))
+}
+
+type hotspotKeys struct {
+	n, hot     int
+	opFraction float64
+}
+
+func (h hotspotKeys) next(r *rand.Rand) int {
+	if r.Float64() < h.opFraction || h.hot == h.n {
+		return r.Intn(h.hot)
+	}
+	return h.hot + r.Intn(h.n-h.hot)
+}
+
+// latestKeys prefers the keys just below the one returned by latest.
+type latestKeys struct {
+	z      *zipfian
+	latest func() int
+}
+
+func (l latestKeys) next(r *rand.Rand) int {
+	k := l.latest() - l.z.next(r)
+	if k < 0 {
+		k = 0
+	}
+	return k
+}
+
+// newKeyChooser returns a chooser for the distribution dist over n keys,
+// or nil for "sequential". latest returns the highest key written so far,
+// if it is nil the highest key is n-1.
+func newKeyChooser(dist string, n int, latest func() int) keyChooser {
+	if n < 1 {
+		n = 1
+	}
+	switch dist {
+	case "sequential":
+		return nil
+	case "uniform":
+		return uniformKeys{n}
+	case "zipfian":
+		return scrambledZipfian{newZipfian(n, zipfianTheta)}
+	case "hotspot":
+		hot := int(math.Max(1, math.Min(float64(n), math.Round(float64(n)*hotspotSetFraction))))
+		return hotspotKeys{n, hot, hotspotOpFraction}
+	case "latest":
+		if latest == nil {
+			latest = func() int { return n - 1 }
+		}
+		return latestKeys{newZipfian(n, zipfianTheta), latest}
+	}
+	log.Fatalf("Unknown key distribution %s", dist)
+	return nil
+}
+
+func fnvHash(n int) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(strconv.Itoa(n)))
+	return h.Sum64()
+}
+
+// workerRands hands out one random generator per worker, since
+// rand.Rand must not be shared between goroutines. Workers are told apart
+// by their base. Every generator is stored once, by the first request of
+// its worker, and only read afterwards, which sync.Map does without a
+// lock, so that the workers do not contend on every request.
+type workerRands struct {
+	rands sync.Map // base -> *rand.Rand
+}
+
+func (w *workerRands) get(base int) *rand.Rand {
+	if r, ok := w.rands.Load(base); ok {
+		return r.(*rand.Rand)
+	}
+	s := seed
+	if s == 0 {
+		s = time.Now().UnixNano()
+	}
+	r, _ := w.rands.LoadOrStore(base, rand.New(rand.NewSource(s+int64(base))))
+	return r.(*rand.Rand)
+}
+
+// docKeys chooses the keys of the seeded documents for a workload
+// according to -keyDistribution.
+type docKeys struct {
+	chooser keyChooser
+	rands   workerRands
+}
+
+// newDocKeys chooses among -keySpace keys, or n if that is not set.
+func newDocKeys(n int) *docKeys {
+	if keySpace > 0 {
+		n = keySpace
+	}
+	return &docKeys{chooser: newKeyChooser(keyDistribution, n, nil)}
+}
+
+// key returns the next key for the worker with base, without a
+// distribution that is "K"+sequential.
+func (k *docKeys) key(base, sequential int) string {
+	if k.chooser == nil {
+		return "K" + strconv.Itoa(sequential)
+	}
+	return "K" + strconv.Itoa(k.chooser.next(k.rands.get(base)))
+}
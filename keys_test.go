@@ -0,0 +1,92 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestKeyChooserRanges(t *testing.T) {
+	latest := 500
+	tests := []struct {
+		dist   string
+		n      int
+		latest func() int
+		max    int // largest possible key
+	}{
+		{"uniform", 1000, nil, 999},
+		{"zipfian", 1000, nil, 999},
+		{"hotspot", 1000, nil, 999},
+		{"latest", 1000, nil, 999},
+		{"latest", 1000, func() int { return latest }, 500},
+		{"uniform", 1, nil, 0},
+		{"zipfian", 0, nil, 0},
+	}
+	for _, tt := range tests {
+		c := newKeyChooser(tt.dist, tt.n, tt.latest)
+		r := rand.New(rand.NewSource(1))
+		for i := 0; i < 100000; i++ {
+			if k := c.next(r); k < 0 || k > tt.max {
+				t.Fatalf("%s over %d keys chose %d", tt.dist, tt.n, k)
+			}
+		}
+	}
+	if newKeyChooser("sequential", 1000, nil) != nil {
+		t.Errorf("sequential has a chooser")
+	}
+}
+
+// keyCounts draws from c and counts how often every key comes up.
+func keyCounts(c keyChooser, n, draws int) []int {
+	r := rand.New(rand.NewSource(1))
+	count := make([]int, n)
+	for i := 0; i < draws; i++ {
+		count[c.next(r)]++
+	}
+	return count
+}
+
+func TestZipfianSkew(t *testing.T) {
+	const n, draws = 1000, 100000
+	count := keyCounts(newZipfian(n, 0.99), n, draws)
+	// Key 0 is the most popular one, far above the uniform share.
+	for k := 1; k < n; k++ {
+		if count[k] > count[0] {
+			t.Fatalf("key %d came up %d times, more than key 0 with %d", k, count[k], count[0])
+		}
+	}
+	if count[0] < 10*draws/n {
+		t.Errorf("key 0 came up only %d times", count[0])
+	}
+}
+
+func TestHotspotFraction(t *testing.T) {
+	const n, draws = 1000, 100000
+	c := hotspotKeys{n: n, hot: 200, opFraction: 0.8}
+	hot := 0
+	for k, cnt := range keyCounts(c, n, draws) {
+		if k < 200 {
+			hot += cnt
+		}
+	}
+	if f := float64(hot) / draws; f < 0.78 || f > 0.82 {
+		t.Errorf("%.3f of the requests went to hot keys, want 0.8", f)
+	}
+}
+
+func TestLatestPrefersRecentKeys(t *testing.T) {
+	const n, draws = 1000, 100000
+	count := keyCounts(newKeyChooser("latest", n, nil), n, draws)
+	if count[n-1] < count[0] || count[n-1] < 10*draws/n {
+		t.Errorf("latest key came up %d times, key 0 %d times", count[n-1], count[0])
+	}
+}
+
+func TestDocKeysSequential(t *testing.T) {
+	saved := keyDistribution
+	defer func() { keyDistribution = saved }()
+	keyDistribution = "sequential"
+	k := newDocKeys(100)
+	if got := k.key(10, 17); got != "K17" {
+		t.Errorf("key(10, 17) = %s, want K17", got)
+	}
+}
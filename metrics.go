@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Live request metrics. Every request issued by runWorkers is recorded
+// here, labelled by testcase, protocol and endpoint. With -metricsAddr
+// the numbers are served in the Prometheus text format on /metrics while
+// the benchmark runs.
+
+// latencyBuckets are the upper bounds in seconds of the request duration
+// histogram.
+var latencyBuckets = []float64{
+	0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01,
+	0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+}
+
+// opMetrics are the counters for a single testcase.
+type opMetrics struct {
+	count   uint64
+	errors  uint64
+	sum     float64  // total latency in seconds
+	buckets []uint64 // per bucket counts, the last one is +Inf
+	maxSecs float64  // largest latency since the last snapshot
+	workers int      // number of workers currently running the testcase
+}
+
+var (
+	metricsMutex sync.Mutex
+	metricsOps   = map[string]*opMetrics{}
+)
+
+// opMetricsFor returns the counters for tc, metricsMutex must be held.
+func opMetricsFor(tc string) *opMetrics {
+	m, ok := metricsOps[tc]
+	if !ok {
+		m = &opMetrics{buckets: make([]uint64, len(latencyBuckets)+1)}
+		metricsOps[tc] = m
+	}
+	return m
+}
+
+// setWorkers records that n workers are running testcase tc.
+func setWorkers(tc string, n int) {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+	opMetricsFor(tc).workers = n
+}
+
+// observe records one request of testcase tc which took d. Requests of
+// scenario phases which are not measured are left out.
+func observe(tc string, d time.Duration, err error) {
+	if !measuring {
+		return
+	}
+	secs := d.Seconds()
+	b := sort.SearchFloat64s(latencyBuckets, secs)
+
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+	m := opMetricsFor(tc)
+	m.count++
+	if err != nil {
+		m.errors++
+	}
+	m.sum += secs
+	m.buckets[b]++
+	if secs > m.maxSecs {
+		m.maxSecs = secs
+	}
+}
+
+// countError records that a request of testcase tc, which observe saw
+// succeed since it does not fail the run, did not have the intended
+// effect.
+func countError(tc string) {
+	if !measuring {
+		return
+	}
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+	opMetricsFor(tc).errors++
+}
+
+// snapshotMetrics returns a copy of the counters of all testcases and
+// starts a new interval for the per interval maximum.
+func snapshotMetrics() map[string]opMetrics {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+	snap := make(map[string]opMetrics, len(metricsOps))
+	for tc, m := range metricsOps {
+		c := *m
+		c.buckets = append([]uint64(nil), m.buckets...)
+		snap[tc] = c
+		m.maxSecs = 0
+	}
+	return snap
+}
+
+// escapeLabel escapes a label value for the Prometheus text format.
+func escapeLabel(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	return strings.Replace(s, "\n", `\n`, -1)
+}
+
+// metricLabels returns the label set for testcase tc, extra is appended
+// verbatim and may be empty.
+func metricLabels(tc string, extra string) string {
+	l := fmt.Sprintf(`testcase="%s",protocol="%s",endpoint="%s"`,
+		escapeLabel(tc), escapeLabel(protocol), escapeLabel(endpoint))
+	if extra != "" {
+		l += "," + extra
+	}
+	return "{" + l + "}"
+}
+
+// writeMetrics writes all metrics in the Prometheus text format.
+func writeMetrics(w io.Writer) {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	names := make([]string, 0, len(metricsOps))
+	for tc := range metricsOps {
+		names = append(names, tc)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "# HELP gobench_requests_total Number of requests issued.")
+	fmt.Fprintln(w, "# TYPE gobench_requests_total counter")
+	for _, tc := range names {
+		fmt.Fprintf(w, "gobench_requests_total%s %d\n", metricLabels(tc, ""), metricsOps[tc].count)
+	}
+	fmt.Fprintln(w, "# HELP gobench_request_errors_total Number of failed requests.")
+	fmt.Fprintln(w, "# TYPE gobench_request_errors_total counter")
+	for _, tc := range names {
+		fmt.Fprintf(w, "gobench_request_errors_total%s %d\n", metricLabels(tc, ""), metricsOps[tc].errors)
+	}
+	fmt.Fprintln(w, "# HELP gobench_workers Number of workers running the testcase.")
+	fmt.Fprintln(w, "# TYPE gobench_workers gauge")
+	for _, tc := range names {
+		fmt.Fprintf(w, "gobench_workers%s %d\n", metricLabels(tc, ""), metricsOps[tc].workers)
+	}
+	fmt.Fprintln(w, "# HELP gobench_request_duration_seconds Client observed request latency.")
+	fmt.Fprintln(w, "# TYPE gobench_request_duration_seconds histogram")
+	for _, tc := range names {
+		m := metricsOps[tc]
+		var cum uint64
+		for i, le := range latencyBuckets {
+			cum += m.buckets[i]
+			fmt.Fprintf(w, "gobench_request_duration_seconds_bucket%s %d\n",
+				metricLabels(tc, fmt.Sprintf(`le="%g"`, le)), cum)
+		}
+		cum += m.buckets[len(latencyBuckets)]
+		fmt.Fprintf(w, "gobench_request_duration_seconds_bucket%s %d\n",
+			metricLabels(tc, `le="+Inf"`), cum)
+		fmt.Fprintf(w, "gobench_request_duration_seconds_sum%s %g\n", metricLabels(tc, ""), m.sum)
+		fmt.Fprintf(w, "gobench_request_duration_seconds_count%s %d\n", metricLabels(tc, ""), m.count)
+	}
+}
+
+// serveMetrics starts an HTTP server on addr answering /metrics.
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetrics(w)
+	})
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Fatalf("Failed to serve metrics: %v", err)
+		}
+	}()
+	log.Printf("Serving metrics on http://%s/metrics", addr)
+}
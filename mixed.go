@@ -0,0 +1,135 @@
+package main
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	driver "github.com/arangodb/go-driver"
+)
+
+// The "mixed" testcase runs several workloads at the same time against
+// the same server, each with its own workers and rate, e.g.
+//
+//	-testcase=mixed -mix=readDocs=80,replaceDocs=15,readThreeDiamondAQL=5
+//
+// splits -parallelism, -nrRequests and -rate 80:15:5 between the three
+// workloads. Each workload chooses its keys among its own share of
+// -nrRequests, or -keySpace keys. Statistics are reported per workload
+// and combined.
+
+// mixEntry is one workload of a mixed run. Parallelism, Rate and
+// NrRequests default to the Share of the overall load settings, KeySpace
+// to -keySpace.
+type mixEntry struct {
+	Testcase    string  `yaml:"testcase"`
+	Share       float64 `yaml:"share"`
+	Parallelism int     `yaml:"parallelism"`
+	Rate        float64 `yaml:"rate"`
+	NrRequests  int     `yaml:"nrRequests"`
+	KeySpace    int     `yaml:"keySpace"`
+}
+
+// parseMix parses a comma separated list of testcase=share pairs, the
+// share may be left out.
+func parseMix(s string) []mixEntry {
+	var entries []mixEntry
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		e := mixEntry{Testcase: part}
+		if pos := strings.Index(part, "="); pos >= 0 {
+			share, err := strconv.ParseFloat(part[pos+1:], 64)
+			if err != nil || share < 0 {
+				log.Fatalf("Invalid share in -mix entry %s", part)
+			}
+			e.Testcase, e.Share = part[:pos], share
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) == 0 {
+		log.Fatalf("-testcase=mixed needs -mix")
+	}
+	return entries
+}
+
+// runMixed runs the workloads of entries concurrently and returns the
+// time at which they were started.
+func runMixed(c driver.Client, col driver.Collection, entries []mixEntry, l loadSettings) time.Time {
+	var total float64
+	for _, e := range entries {
+		total += e.Share
+	}
+	workloads := make([]workload, len(entries))
+	loads := make([]loadSettings, len(entries))
+	savedNrRequests, savedKeySpace := nrRequests, keySpace
+	defer func() { nrRequests, keySpace = savedNrRequests, savedKeySpace }()
+	for i, e := range entries {
+		share := 1 / float64(len(entries))
+		if total > 0 {
+			share = e.Share / total
+		}
+		el := l
+		el.parallelism = int(math.Max(1, math.Round(float64(l.parallelism)*share)))
+		el.nrRequests = int(math.Round(float64(l.nrRequests) * share))
+		el.rate = l.rate * share
+		if e.Parallelism > 0 {
+			el.parallelism = e.Parallelism
+		}
+		if e.NrRequests > 0 {
+			el.nrRequests = e.NrRequests
+		}
+		if e.Rate > 0 {
+			el.rate = e.Rate
+		}
+		loads[i] = el
+
+		// The workload chooses its keys among its own requests, or its
+		// own key space:
+		nrRequests, keySpace = el.nrRequests, savedKeySpace
+		if e.KeySpace > 0 {
+			keySpace = e.KeySpace
+		}
+		w, ok := newWorkload(c, col, e.Testcase)
+		if !ok {
+			log.Fatalf("Unknown testcase %s in mix", e.Testcase)
+		}
+		checkDuration(w.tc, l.duration, overwriteMode)
+		workloads[i] = w
+		log.Printf("Mixing %s with %d workers, %d requests, rate %.1f/s",
+			w.tc, el.parallelism, el.nrRequests, el.rate)
+	}
+
+	startTime := time.Now()
+	times := make([][]time.Duration, len(entries))
+	wg := sync.WaitGroup{}
+	for i := range workloads {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			times[i] = runWorkers(workloads[i].tc, loads[i], workloads[i].op)
+		}(i)
+	}
+	wg.Wait()
+
+	var combined []time.Duration
+	var combinedPar int
+	for i, w := range workloads {
+		submittedRequests += len(times[i])
+		logStats(w.tc, "mixed "+w.name, loads[i].parallelism, times[i])
+		combined = append(combined, times[i]...)
+		combinedPar += loads[i].parallelism
+	}
+	logStats("mixed", "mixed combined ops", combinedPar, combined)
+	for i, w := range workloads {
+		if w.done != nil {
+			w.done(loads[i].parallelism)
+		}
+	}
+	return startTime
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMix(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []mixEntry
+	}{
+		{"readDocs", []mixEntry{{Testcase: "readDocs"}}},
+		{"readDocs=80,replaceDocs=15,readThreeDiamondAQL=5", []mixEntry{
+			{Testcase: "readDocs", Share: 80},
+			{Testcase: "replaceDocs", Share: 15},
+			{Testcase: "readThreeDiamondAQL", Share: 5},
+		}},
+		{" readDocs=0.5 , ,replaceDocs", []mixEntry{
+			{Testcase: "readDocs", Share: 0.5},
+			{Testcase: "replaceDocs"},
+		}},
+	}
+	for _, tt := range tests {
+		if got := parseMix(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseMix(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"math/rand"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	driver "github.com/arangodb/go-driver"
+)
+
+// The raw testcase sends arbitrary requests through the chosen
+// connection, to benchmark APIs gobench has no testcase for. The request
+// is -rawMethod -rawPath with the JSON body -rawBody, and the response
+// status has to be one of -rawStatus. -rawFile instead names a file with
+// one request template per line as JSON object
+//
+//	{"name": "cursor", "method": "POST", "path": "/_db/benchDB/_api/cursor",
+//	 "body": {"query": "RETURN {{i}}"}, "status": [201], "weight": 2}
+//
+// of which every request picks one at random, in proportion to their
+// weights (default 1). Empty lines and lines starting with # are
+// skipped. In paths and bodies {{i}} is replaced by the number of the
+// request, {{key}} by a key "K<n>" of the seeded documents chosen by
+// -keyDistribution and {{random}} by a random number. Latencies are
+// reported per template.
+
+var (
+	rawMethod string = "GET"
+	rawPath   string = "/_api/version"
+	rawBody   string = ""    // JSON
+	rawStatus string = "200" // comma separated
+	rawFile   string = ""    // request templates, one per line
+)
+
+// rawTemplate is a request of the raw testcase.
+type rawTemplate struct {
+	Name   string          `json:"name"`
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body"`
+	Status []int           `json:"status"`
+	Weight float64         `json:"weight"`
+
+	body     interface{} // parsed Body if it has no placeholders
+	parsed   bool
+	needsKey bool // whether Path or Body contain {{key}}
+}
+
+// prepare parses the body of t once if it has no placeholders, so that
+// requests do not need to do it.
+func (t *rawTemplate) prepare() {
+	t.needsKey = strings.Contains(t.Path, "{{key}}") || strings.Contains(string(t.Body), "{{key}}")
+	if len(t.Body) == 0 || strings.Contains(string(t.Body), "{{") {
+		return
+	}
+	if err := json.Unmarshal(t.Body, &t.body); err != nil {
+		log.Fatalf("Invalid body of raw request %s: %v", t.Name, err)
+	}
+	t.parsed = true
+}
+
+// readRawTemplates returns the templates of -rawFile, or the one given by
+// the other flags with path p.
+func readRawTemplates(p string) []rawTemplate {
+	if rawFile == "" {
+		t := rawTemplate{Name: rawMethod + " " + p, Method: rawMethod, Path: p, Weight: 1}
+		if rawBody != "" {
+			t.Body = json.RawMessage(rawBody)
+		}
+		for _, s := range strings.Split(rawStatus, ",") {
+			code, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				log.Fatalf("Invalid -rawStatus %s", rawStatus)
+			}
+			t.Status = append(t.Status, code)
+		}
+		return []rawTemplate{t}
+	}
+	f, err := os.Open(rawFile)
+	if err != nil {
+		log.Fatalf("Failed to open raw requests: %v", err)
+	}
+	defer f.Close()
+	var templates []rawTemplate
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var t rawTemplate
+		if err := json.Unmarshal([]byte(line), &t); err != nil {
+			log.Fatalf("Invalid raw request %s: %v", line, err)
+		}
+		if t.Method == "" {
+			t.Method = "GET"
+		}
+		if t.Name == "" {
+			t.Name = t.Method + " " + t.Path
+		}
+		if len(t.Status) == 0 {
+			t.Status = []int{200}
+		}
+		if t.Weight == 0 {
+			t.Weight = 1
+		}
+		templates = append(templates, t)
+	}
+	if err = scanner.Err(); err != nil {
+		log.Fatalf("Failed to read raw requests: %v", err)
+	}
+	if len(templates) == 0 {
+		log.Fatalf("No requests in %s", rawFile)
+	}
+	return templates
+}
+
+// fill replaces the placeholders in s.
+func (t *rawTemplate) fill(s string, r *rand.Rand, i int, key string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	s = strings.Replace(s, "{{i}}", strconv.Itoa(i), -1)
+	s = strings.Replace(s, "{{key}}", key, -1)
+	return strings.Replace(s, "{{random}}", strconv.FormatInt(r.Int63(), 10), -1)
+}
+
+// send sends the request number i of template t through conn, with key
+// for {{key}}.
+func (t *rawTemplate) send(conn driver.Connection, r *rand.Rand, i int, key string) error {
+	p := t.fill(t.Path, r, i, key)
+	var query url.Values
+	if pos := strings.Index(p, "?"); pos >= 0 {
+		var err error
+		if query, err = url.ParseQuery(p[pos+1:]); err != nil {
+			return err
+		}
+		p = p[:pos]
+	}
+	req, err := conn.NewRequest(t.Method, p)
+	if err != nil {
+		return err
+	}
+	for k, vs := range query {
+		for _, v := range vs {
+			req.SetQuery(k, v)
+		}
+	}
+	if len(t.Body) > 0 {
+		body := t.body
+		if !t.parsed {
+			if err = json.Unmarshal([]byte(t.fill(string(t.Body), r, i, key)), &body); err != nil {
+				return err
+			}
+		}
+		if _, err = req.SetBody(body); err != nil {
+			return err
+		}
+	}
+	resp, err := conn.Do(nil, req)
+	if err != nil {
+		return err
+	}
+	return resp.CheckStatus(t.Status...)
+}
+
+func rawWorkload(c driver.Client) workload {
+	return templatesWorkload(c, "raw", readRawTemplates(rawPath))
+}
+
+// templatesWorkload sends requests from templates as testcase tc.
+func templatesWorkload(c driver.Client, tc string, templates []rawTemplate) workload {
+	var total float64
+	for j := range templates {
+		templates[j].prepare()
+		total += templates[j].Weight
+	}
+	conn := c.Connection()
+	keys := newDocKeys(nrRequests)
+	var rands workerRands
+	var ops opTimes
+	op := func(base, i int) error {
+		r := rands.get(base)
+		t := &templates[len(templates)-1]
+		for j, w := 0, r.Float64()*total; j < len(templates); j++ {
+			if w -= templates[j].Weight; w < 0 {
+				t = &templates[j]
+				break
+			}
+		}
+		var key string
+		if t.needsKey {
+			key = keys.key(base, base+i)
+		}
+		startTime := time.Now()
+		err := t.send(conn, r, base+i, key)
+		if err == nil {
+			ops.add(t.Name, time.Since(startTime))
+		}
+		return err
+	}
+	done := func(par int) {
+		if len(templates) > 1 {
+			ops.log(tc, tc, par)
+		}
+	}
+	name := tc + " ops"
+	if len(templates) == 1 {
+		name = tc + " " + templates[0].Name + " ops"
+	}
+	return workload{tc, name, op, done}
+}
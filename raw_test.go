@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestRawFill(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"/_api/version", "/_api/version"},
+		{"/_api/document/test/{{key}}", "/_api/document/test/K7"},
+		{`{"query": "RETURN [{{i}}, '{{key}}', '{{key}}']"}`, `{"query": "RETURN [42, 'K7', 'K7']"}`},
+		{"/{{unknown}}", "/{{unknown}}"},
+	}
+	var tmpl rawTemplate
+	for _, tt := range tests {
+		if got := tmpl.fill(tt.in, rand.New(rand.NewSource(1)), 42, "K7"); got != tt.want {
+			t.Errorf("fill(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	r := rand.New(rand.NewSource(1))
+	want := strconv.FormatInt(rand.New(rand.NewSource(1)).Int63(), 10)
+	if got := tmpl.fill("{{random}}", r, 0, ""); got != want {
+		t.Errorf("fill({{random}}) = %q, want %q", got, want)
+	}
+}
+
+func TestRawPrepare(t *testing.T) {
+	tests := []struct {
+		path, body string
+		needsKey   bool
+		parsed     interface{} // nil if the body is parsed per request
+	}{
+		{"/_api/version", "", false, nil},
+		{"/_api/document/test/{{key}}", "", true, nil},
+		{"/_api/cursor", `{"query": "RETURN 1"}`, false, map[string]interface{}{"query": "RETURN 1"}},
+		{"/_api/cursor", `{"query": "RETURN DOCUMENT('test/{{key}}')"}`, true, nil},
+		{"/_api/cursor", `{"query": "RETURN {{i}}"}`, false, nil},
+	}
+	for _, tt := range tests {
+		tmpl := rawTemplate{Name: tt.path, Path: tt.path}
+		if tt.body != "" {
+			tmpl.Body = json.RawMessage(tt.body)
+		}
+		tmpl.prepare()
+		if tmpl.needsKey != tt.needsKey {
+			t.Errorf("%s %s: needsKey = %v, want %v", tt.path, tt.body, tmpl.needsKey, tt.needsKey)
+		}
+		if tmpl.parsed != (tt.parsed != nil) || !reflect.DeepEqual(tmpl.body, tt.parsed) {
+			t.Errorf("%s %s: parsed body %v, want %v", tt.path, tt.body, tmpl.body, tt.parsed)
+		}
+	}
+}
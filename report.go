@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HTML reports. A report is a single self-contained HTML file with
+// tables and SVG charts for one or more runs. -reportData saves the data
+// of a run as JSON, -reportFrom renders a report for a sweep from several
+// such files without running a benchmark.
+
+// runData is everything the report shows about one run.
+type runData struct {
+	Title         string          `json:"title"`
+	Start         time.Time       `json:"start"`
+	Endpoint      string          `json:"endpoint"`
+	Protocol      string          `json:"protocol"`
+	ContentType   string          `json:"contentType"`
+	TLS           bool            `json:"tls"`
+	NrConnections int             `json:"nrConnections"`
+	Parallelism   int             `json:"parallelism"`
+	NrRequests    int             `json:"nrRequests"`
+	ServerVersion string          `json:"serverVersion"`
+	Results       []result        `json:"results"`
+	Intervals     []intervalPoint `json:"intervals"`
+
+	CollectionWaitForSync bool   `json:"collectionWaitForSync"`
+	WaitForSync           bool   `json:"waitForSync"`
+	WriteConcern          int    `json:"writeConcern"`
+	NumberOfShards        int    `json:"numberOfShards"`
+	OverwriteMode         string `json:"overwriteMode"`
+
+	ShardKeys            string `json:"shardKeys"`
+	DistributeShardsLike string `json:"distributeShardsLike"`
+	Satellite            bool   `json:"satellite"`
+	SmartJoinAttribute   string `json:"smartJoinAttribute"`
+	Smart                bool   `json:"smart"`
+	SmartGraphAttribute  string `json:"smartGraphAttribute"`
+}
+
+// collectRunData gathers the data of the current run.
+func collectRunData(start time.Time) runData {
+	resultsMutex.Lock()
+	defer resultsMutex.Unlock()
+	return runData{
+		Title: fmt.Sprintf("%s %s/%s tls=%v parallelism=%d connections=%d%s",
+			testcase, protocol, contentType, usetls, parallelism, nrConnections, durabilityName()+shardingName()),
+		Start:         start,
+		Endpoint:      endpoint,
+		Protocol:      protocol,
+		ContentType:   contentType,
+		TLS:           usetls,
+		NrConnections: nrConnections,
+		Parallelism:   parallelism,
+		NrRequests:    nrRequests,
+		ServerVersion: serverVersion,
+		Results:       append([]result(nil), results...),
+		Intervals:     append([]intervalPoint(nil), intervals...),
+
+		CollectionWaitForSync: collectionWaitForSync,
+		WaitForSync:           waitForSync,
+		WriteConcern:          writeConcern,
+		NumberOfShards:        numberOfShards,
+		OverwriteMode:         overwriteMode,
+
+		ShardKeys:            shardKeys,
+		DistributeShardsLike: distributeShardsLike,
+		Satellite:            satellite,
+		SmartJoinAttribute:   smartJoinAttribute,
+		Smart:                smart,
+		SmartGraphAttribute:  smartGraphAttribute,
+	}
+}
+
+func writeRunData(path string, run runData) {
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to encode report data: %v", err)
+	}
+	if err = ioutil.WriteFile(path, data, 0644); err != nil {
+		log.Fatalf("Failed to write report data: %v", err)
+	}
+	log.Printf("Wrote report data to %s", path)
+}
+
+// readRunData reads the comma separated list of files written by
+// writeRunData.
+func readRunData(paths string) []runData {
+	var runs []runData
+	for _, path := range strings.Split(paths, ",") {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Failed to read report data: %v", err)
+		}
+		var run runData
+		if err = json.Unmarshal(data, &run); err != nil {
+			log.Fatalf("Failed to parse report data %s: %v", path, err)
+		}
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// chartColors are used for the series of a chart in turn.
+var chartColors = []string{
+	"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd",
+	"#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf",
+}
+
+// chartSeries is one line of a chart.
+type chartSeries struct {
+	Name string
+	X, Y []float64
+}
+
+// chartAxis maps values onto a chart axis, optionally logarithmically.
+type chartAxis struct {
+	min, max float64
+	log      bool
+	label    func(v float64) string
+}
+
+func (a chartAxis) pos(v float64, length float64) float64 {
+	if a.log {
+		v, min, max := math.Log10(v), math.Log10(a.min), math.Log10(a.max)
+		return (v - min) / (max - min) * length
+	}
+	return (v - a.min) / (a.max - a.min) * length
+}
+
+// ticks returns the positions of the grid lines, the decades for a
+// logarithmic axis and steps of 1, 2 or 5 times a power of ten otherwise.
+func (a chartAxis) ticks() []float64 {
+	var t []float64
+	if a.max <= a.min {
+		return t
+	}
+	if a.log {
+		for v := math.Pow(10, math.Ceil(math.Log10(a.min))); v <= a.max*1.0001; v *= 10 {
+			t = append(t, v)
+		}
+		return t
+	}
+	raw := (a.max - a.min) / 5
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := mag
+	for _, m := range []float64{2, 5, 10} {
+		if step >= raw {
+			break
+		}
+		step = mag * m
+	}
+	for k := math.Ceil(a.min / step); k*step <= a.max+step/1000; k++ {
+		// Round away the error of the multiplication for the labels.
+		t = append(t, math.Round(k*step/mag*1000)*mag/1000)
+	}
+	return t
+}
+
+const (
+	chartWidth  = 720
+	chartHeight = 300
+	chartLeft   = 70
+	chartBottom = 40
+	chartTop    = 30
+	chartRight  = 160 // room for the legend
+)
+
+// svgChart draws the series as lines, or as bars if bars is set.
+func svgChart(title string, xAxis, yAxis chartAxis, series []chartSeries, bars bool) template.HTML {
+	w := float64(chartWidth - chartLeft - chartRight)
+	h := float64(chartHeight - chartTop - chartBottom)
+	// Grow the picture if the legend does not fit.
+	width, height := chartWidth, chartHeight
+	for i, s := range series {
+		if n := chartWidth - chartRight + 30 + 7*len(s.Name); n > width {
+			width = n
+		}
+		if n := chartTop + 16*(i+1); n > height {
+			height = n
+		}
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="11">`,
+		width, height)
+	fmt.Fprintf(&b, `<text x="%d" y="18" font-size="13" font-weight="bold">%s</text>`,
+		chartLeft, template.HTMLEscapeString(title))
+	fmt.Fprintf(&b, `<g transform="translate(%d,%d)">`, chartLeft, chartTop)
+	for _, t := range yAxis.ticks() {
+		y := h - yAxis.pos(t, h)
+		fmt.Fprintf(&b, `<line x1="0" x2="%.1f" y1="%.1f" y2="%.1f" stroke="#ddd"/>`, w, y, y)
+		fmt.Fprintf(&b, `<text x="-6" y="%.1f" text-anchor="end" dominant-baseline="middle">%s</text>`,
+			y, template.HTMLEscapeString(yAxis.label(t)))
+	}
+	for _, t := range xAxis.ticks() {
+		x := xAxis.pos(t, w)
+		fmt.Fprintf(&b, `<line x1="%.1f" x2="%.1f" y1="0" y2="%.1f" stroke="#ddd"/>`, x, x, h)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle">%s</text>`,
+			x, h+16, template.HTMLEscapeString(xAxis.label(t)))
+	}
+	fmt.Fprintf(&b, `<rect x="0" y="0" width="%.1f" height="%.1f" fill="none" stroke="#888"/>`, w, h)
+	for i, s := range series {
+		color := chartColors[i%len(chartColors)]
+		if bars {
+			bw := w / float64(len(s.X)) * 0.9 / float64(len(series))
+			for j := range s.X {
+				x := xAxis.pos(s.X[j], w) - bw*float64(len(series))/2 + bw*float64(i)
+				y := h - yAxis.pos(s.Y[j], h)
+				fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"/>`,
+					x, y, bw, h-y, color)
+			}
+		} else {
+			var pts []string
+			for j := range s.X {
+				pts = append(pts, fmt.Sprintf("%.1f,%.1f", xAxis.pos(s.X[j], w), h-yAxis.pos(s.Y[j], h)))
+			}
+			fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1.5"/>`,
+				strings.Join(pts, " "), color)
+		}
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%d" width="10" height="10" fill="%s"/>`, w+10, i*16, color)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d">%s</text>`, w+24, i*16+9, template.HTMLEscapeString(s.Name))
+	}
+	b.WriteString(`</g></svg>`)
+	return template.HTML(b.String())
+}
+
+func formatMillis(v float64) string {
+	return fmt.Sprintf("%gms", math.Round(v*1000)/1000)
+}
+
+// spectrumChart plots the latency over the percentile, the x axis shows
+// 1/(1-p) logarithmically, like HdrHistogram does.
+func spectrumChart(title string, names []string, res []result) template.HTML {
+	var series []chartSeries
+	maxX, maxY := 10.0, 0.0
+	for i, r := range res {
+		s := chartSeries{Name: names[i]}
+		for _, p := range r.Spectrum {
+			x := 1 / (1 - p.Percentile/100)
+			if p.Percentile >= 100 {
+				// Put the maximum a bit further out than the last percentile.
+				x = 2
+				if len(s.X) > 0 {
+					x = 2 * s.X[len(s.X)-1]
+				}
+			}
+			y := float64(p.Latency) / float64(time.Millisecond)
+			s.X = append(s.X, x)
+			s.Y = append(s.Y, y)
+			maxX = math.Max(maxX, x)
+			maxY = math.Max(maxY, y)
+		}
+		series = append(series, s)
+	}
+	xAxis := chartAxis{min: 1, max: maxX, log: true, label: func(v float64) string {
+		return fmt.Sprintf("%g%%", 100-100/v)
+	}}
+	yAxis := chartAxis{min: 0, max: math.Max(maxY, 0.001), label: formatMillis}
+	return svgChart(title, xAxis, yAxis, series, false)
+}
+
+// histogramChart draws the latency distribution of one result.
+func histogramChart(r result) template.HTML {
+	s := chartSeries{Name: "requests"}
+	maxY := 1.0
+	for _, hb := range r.Histogram {
+		// Bars are centered between the bucket bounds.
+		s.X = append(s.X, float64(hb.Upper)/float64(time.Millisecond)*math.Pow(10, -0.05))
+		s.Y = append(s.Y, float64(hb.Count))
+		maxY = math.Max(maxY, float64(hb.Count))
+	}
+	minX := s.X[0] * math.Pow(10, -0.1)
+	maxX := s.X[len(s.X)-1] * math.Pow(10, 0.1)
+	if maxX/minX < 10 {
+		// Make sure that there is at least one decade to label.
+		minX, maxX = math.Pow(10, math.Floor(math.Log10(minX))), math.Pow(10, math.Ceil(math.Log10(maxX)))
+	}
+	xAxis := chartAxis{min: minX, max: maxX, log: true, label: formatMillis}
+	yAxis := chartAxis{min: 0, max: maxY, label: func(v float64) string { return fmt.Sprintf("%g", v) }}
+	return svgChart("Latency distribution: "+r.Name, xAxis, yAxis, []chartSeries{s}, true)
+}
+
+// throughputChart shows the requests per second of every testcase over
+// the time since the start of the run.
+func throughputChart(run runData) template.HTML {
+	if len(run.Intervals) == 0 {
+		return ""
+	}
+	start := run.Intervals[0].Time.Add(-run.Intervals[0].Interval)
+	byTestcase := map[string]*chartSeries{}
+	var order []string
+	maxX, maxY := 1.0, 1.0
+	for _, p := range run.Intervals {
+		s, ok := byTestcase[p.Testcase]
+		if !ok {
+			s = &chartSeries{Name: p.Testcase}
+			byTestcase[p.Testcase] = s
+			order = append(order, p.Testcase)
+		}
+		x := p.Time.Sub(start).Seconds()
+		y := float64(p.Requests) / p.Interval.Seconds()
+		s.X = append(s.X, x)
+		s.Y = append(s.Y, y)
+		maxX = math.Max(maxX, x)
+		maxY = math.Max(maxY, y)
+	}
+	var series []chartSeries
+	for _, tc := range order {
+		series = append(series, *byTestcase[tc])
+	}
+	xAxis := chartAxis{min: 0, max: maxX, label: func(v float64) string { return fmt.Sprintf("%gs", v) }}
+	yAxis := chartAxis{min: 0, max: maxY, label: func(v float64) string { return fmt.Sprintf("%g", v) }}
+	return svgChart("Throughput (requests/s)", xAxis, yAxis, series, false)
+}
+
+// reportRun is a run prepared for the template.
+type reportRun struct {
+	runData
+	Throughput template.HTML
+	Spectrum   template.HTML
+	Histograms []template.HTML
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"rate": func(r result) string {
+		if r.Sum == 0 || r.Parallelism == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.0f", float64(r.Samples)/(r.Sum.Seconds()/float64(r.Parallelism)))
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gobench report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+th { background: #f0f0f0; }
+.params td { text-align: left; }
+svg { display: block; margin: 1em 0; }
+</style>
+</head>
+<body>
+<h1>gobench report</h1>
+{{if .Sweep}}
+<h2>Sweep</h2>
+{{.Sweep}}
+<table>
+<tr><th>run</th><th>testcase</th><th>parallelism</th><th>samples</th><th>req/s</th><th>mean</th><th>median</th><th>99%</th><th>99.9%</th><th>max</th></tr>
+{{range .Runs}}{{$run := .}}{{range .Results}}<tr><td>{{$run.Title}}</td><td>{{.Name}}</td><td>{{.Parallelism}}</td><td>{{.Samples}}</td><td>{{rate .}}</td><td>{{.Mean}}</td><td>{{.Median}}</td><td>{{.P99}}</td><td>{{.P999}}</td><td>{{.Max}}</td></tr>
+{{end}}{{end}}</table>
+{{end}}
+{{range .Runs}}
+<h2>{{.Title}}</h2>
+<table class="params">
+<tr><td>Start</td><td>{{.Start.Format "2006-01-02 15:04:05"}}</td></tr>
+<tr><td>Endpoint</td><td>{{.Endpoint}}</td></tr>
+<tr><td>Protocol</td><td>{{.Protocol}}{{if .ContentType}} ({{.ContentType}}){{end}}{{if .TLS}} with TLS{{end}}</td></tr>
+<tr><td>Connections</td><td>{{.NrConnections}}</td></tr>
+<tr><td>Server version</td><td>{{.ServerVersion}}</td></tr>
+</table>
+<table>
+<tr><th>testcase</th><th>parallelism</th><th>samples</th><th>req/s</th><th>mean</th><th>median</th><th>90%</th><th>99%</th><th>99.9%</th><th>min</th><th>max</th><th>stddev</th></tr>
+{{range .Results}}<tr><td>{{.Name}}</td><td>{{.Parallelism}}</td><td>{{.Samples}}</td><td>{{rate .}}</td><td>{{.Mean}}</td><td>{{.Median}}</td><td>{{.P90}}</td><td>{{.P99}}</td><td>{{.P999}}</td><td>{{.Min}}</td><td>{{.Max}}</td><td>{{.StdDev}}</td></tr>
+{{end}}</table>
+{{.Throughput}}
+{{.Spectrum}}
+{{range .Histograms}}{{.}}{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// writeReport renders the runs into a single HTML file. For more than
+// one run the percentile spectra of all runs are compared at the top.
+func writeReport(path string, runs []runData) {
+	var data struct {
+		Sweep template.HTML
+		Runs  []reportRun
+	}
+	var sweepNames []string
+	var sweepResults []result
+	for _, run := range runs {
+		rr := reportRun{runData: run, Throughput: throughputChart(run)}
+		var names []string
+		for _, r := range run.Results {
+			names = append(names, r.Name)
+			rr.Histograms = append(rr.Histograms, histogramChart(r))
+			sweepNames = append(sweepNames, run.Title+": "+r.Name)
+			sweepResults = append(sweepResults, r)
+		}
+		if len(run.Results) > 0 {
+			rr.Spectrum = spectrumChart("Latency by percentile", names, run.Results)
+		}
+		data.Runs = append(data.Runs, rr)
+	}
+	if len(runs) > 1 && len(sweepResults) > 0 {
+		sort.SliceStable(data.Runs, func(a, b int) bool {
+			return data.Runs[a].Start.Before(data.Runs[b].Start)
+		})
+		data.Sweep = spectrumChart("Latency by percentile, all runs", sweepNames, sweepResults)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatalf("Failed to create report: %v", err)
+	}
+	if err = reportTemplate.Execute(f, data); err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+	log.Printf("Wrote report to %s", path)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"path"
+
+	driver "github.com/arangodb/go-driver"
+)
+
+// dbRequest sends a request for the API relPath, e.g. "_api/index", to
+// the database db. query are the query parameters, body is sent if it is
+// not nil and the response body is parsed into result if that is not
+// nil. Status codes other than 200, 201, 202 and 204 are errors.
+func dbRequest(c driver.Client, db driver.Database, method string, relPath string,
+	query map[string]string, body interface{}, result interface{}) error {
+	conn := c.Connection()
+	req, err := conn.NewRequest(method, path.Join("_db", db.Name(), relPath))
+	if err != nil {
+		return err
+	}
+	for k, v := range query {
+		req.SetQuery(k, v)
+	}
+	if body != nil {
+		if _, err = req.SetBody(body); err != nil {
+			return err
+		}
+	}
+	resp, err := conn.Do(nil, req)
+	if err != nil {
+		return err
+	}
+	if err = resp.CheckStatus(200, 201, 202, 204); err != nil {
+		return err
+	}
+	if result != nil {
+		return resp.ParseBody("", result)
+	}
+	return nil
+}
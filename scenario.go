@@ -0,0 +1,341 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	driver "github.com/arangodb/go-driver"
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario files describe a complete experiment: where to connect, which
+// collections to prepare and an ordered list of phases, each running one
+// testcase with its own load settings. They are YAML, and since JSON is
+// a subset of YAML, JSON files work as well:
+//
+//	endpoints: [https://127.0.0.1:8529]
+//	protocol: HTTP2
+//	useTLS: true
+//	nrConnections: 16
+//	auth: {user: root, pass: ""}
+//	datasets:
+//	  - name: test
+//	    documents: 100000
+//	phases:
+//	  - {name: warmup, kind: warmup, testcase: readDocs, duration: 10s}
+//	  - {name: read, testcase: readDocs, parallelism: 16, rate: 5000, duration: 1m}
+//	  - {name: write, testcase: replaceDocs, parallelism: 16, nrRequests: 100000}
+//	  - name: mixed
+//	    duration: 1m
+//	    workloads:
+//	      - {testcase: readDocs, parallelism: 12, rate: 4000}
+//	      - {testcase: replaceDocs, parallelism: 4, rate: 500}
+//	  - {kind: teardown}
+//
+// Settings left out keep the values of the command line flags. Besides
+// the durability and sharding settings at the top, phases may set
+// waitForSync and overwriteMode for their writes.
+
+// scenario is the contents of a scenario file.
+type scenario struct {
+	Endpoints         []string `yaml:"endpoints"`
+	Protocol          string   `yaml:"protocol"`
+	ContentType       string   `yaml:"contentType"`
+	UseTLS            *bool    `yaml:"useTLS"`
+	NrConnections     int      `yaml:"nrConnections"`
+	ReplicationFactor int      `yaml:"replicationFactor"`
+	Cleanup           *bool    `yaml:"cleanup"`
+	Auth              struct {
+		User string `yaml:"user"`
+		Pass string `yaml:"pass"`
+	} `yaml:"auth"`
+	Datasets []dataset `yaml:"datasets"`
+	Phases   []phase   `yaml:"phases"`
+
+	CollectionWaitForSync *bool  `yaml:"collectionWaitForSync"`
+	WaitForSync           *bool  `yaml:"waitForSync"`
+	WriteConcern          int    `yaml:"writeConcern"`
+	NumberOfShards        int    `yaml:"numberOfShards"`
+	OverwriteMode         string `yaml:"overwriteMode"`
+
+	ShardKeys            []string `yaml:"shardKeys"`
+	DistributeShardsLike string   `yaml:"distributeShardsLike"`
+	Satellite            *bool    `yaml:"satellite"`
+	SmartJoinAttribute   string   `yaml:"smartJoinAttribute"`
+	Smart                *bool    `yaml:"smart"`
+	SmartGraphAttribute  string   `yaml:"smartGraphAttribute"`
+}
+
+// dataset is a collection in benchDB which is created before the first
+// phase and optionally seeded with documents with the keys "K0", "K1", ...
+// like the seedDocs testcase does.
+type dataset struct {
+	Name      string `yaml:"name"`
+	Documents int    `yaml:"documents"`
+}
+
+// phase runs one testcase, or several workloads at the same time like
+// the mixed testcase does. Only the results of "measure" phases are
+// reported, "setup" and "warmup" phases just put load on the server and
+// "teardown" phases drop the datasets after running their testcase, if
+// any.
+type phase struct {
+	Name        string        `yaml:"name"`
+	Kind        string        `yaml:"kind"`
+	Testcase    string        `yaml:"testcase"`
+	Dataset     string        `yaml:"dataset"`
+	NrRequests  int           `yaml:"nrRequests"`
+	Parallelism int           `yaml:"parallelism"`
+	Delay       time.Duration `yaml:"delay"`
+	Rate        float64       `yaml:"rate"`
+	Duration    time.Duration `yaml:"duration"`
+	Workloads   []mixEntry    `yaml:"workloads"`
+
+	WaitForSync   *bool  `yaml:"waitForSync"`
+	OverwriteMode string `yaml:"overwriteMode"`
+}
+
+var (
+	currentPhase string        // name of the running scenario phase
+	measuring    bool   = true // false while a phase is not measured
+)
+
+// loadScenario reads a scenario file and applies its connection settings
+// to the globals.
+func loadScenario(path string) *scenario {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Fatalf("Failed to read scenario: %v", err)
+	}
+	sc := &scenario{}
+	if err = yaml.Unmarshal(data, sc); err != nil {
+		log.Fatalf("Failed to parse scenario %s: %v", path, err)
+	}
+	if len(sc.Phases) == 0 {
+		log.Fatalf("Scenario %s has no phases", path)
+	}
+	for i := range sc.Phases {
+		p := &sc.Phases[i]
+		switch p.Kind {
+		case "":
+			p.Kind = "measure"
+		case "setup", "warmup", "measure", "teardown":
+		default:
+			log.Fatalf("Phase %d has unknown kind %s", i, p.Kind)
+		}
+		if p.Testcase == "" && len(p.Workloads) == 0 && p.Kind != "teardown" {
+			log.Fatalf("Phase %d needs a testcase or workloads", i)
+		}
+		if p.Name == "" {
+			p.Name = p.Kind + strconv.Itoa(i)
+		}
+	}
+
+	if len(sc.Endpoints) > 0 {
+		endpoint = strings.Join(sc.Endpoints, ",")
+	}
+	if sc.Protocol != "" {
+		protocol = sc.Protocol
+	}
+	if sc.ContentType != "" {
+		contentType = sc.ContentType
+	}
+	if sc.UseTLS != nil {
+		usetls = *sc.UseTLS
+	}
+	if sc.NrConnections > 0 {
+		nrConnections = sc.NrConnections
+	}
+	if sc.ReplicationFactor > 0 {
+		replFactor = sc.ReplicationFactor
+	}
+	if sc.Cleanup != nil {
+		cleanup = *sc.Cleanup
+	}
+	if sc.CollectionWaitForSync != nil {
+		collectionWaitForSync = *sc.CollectionWaitForSync
+	}
+	if sc.WaitForSync != nil {
+		waitForSync = *sc.WaitForSync
+	}
+	if sc.WriteConcern > 0 {
+		writeConcern = sc.WriteConcern
+	}
+	if sc.NumberOfShards > 0 {
+		numberOfShards = sc.NumberOfShards
+	}
+	if sc.OverwriteMode != "" {
+		overwriteMode = sc.OverwriteMode
+	}
+	if len(sc.ShardKeys) > 0 {
+		shardKeys = strings.Join(sc.ShardKeys, ",")
+	}
+	if sc.DistributeShardsLike != "" {
+		distributeShardsLike = sc.DistributeShardsLike
+	}
+	if sc.Satellite != nil {
+		satellite = *sc.Satellite
+	}
+	if sc.SmartJoinAttribute != "" {
+		smartJoinAttribute = sc.SmartJoinAttribute
+	}
+	if sc.Smart != nil {
+		smart = *sc.Smart
+	}
+	if sc.SmartGraphAttribute != "" {
+		smartGraphAttribute = sc.SmartGraphAttribute
+	}
+	if sc.Auth.User != "" {
+		username = sc.Auth.User
+		password = sc.Auth.Pass
+	}
+	checkDurabilityFlags()
+	checkShardingFlags()
+	for _, p := range sc.Phases {
+		switch p.OverwriteMode {
+		case "", "replace", "update", "ignore", "conflict":
+		default:
+			log.Fatalf("Phase %s has unknown overwriteMode %s", p.Name, p.OverwriteMode)
+		}
+		d, mode := duration, overwriteMode
+		if p.Duration > 0 {
+			d = p.Duration
+		}
+		if p.OverwriteMode != "" {
+			mode = p.OverwriteMode
+		}
+		checkDuration(p.Testcase, d, mode)
+		for _, e := range p.Workloads {
+			checkDuration(e.Testcase, d, mode)
+		}
+	}
+	testcase = "scenario " + path
+	return sc
+}
+
+// setupDatasets creates the collections of the datasets in db and seeds
+// them. The "test" collection col always exists already.
+func (sc *scenario) setupDatasets(db driver.Database, col driver.Collection) map[string]driver.Collection {
+	cols := map[string]driver.Collection{"test": col}
+	saved := currentLoad()
+	defer saved.apply()
+	for _, ds := range sc.Datasets {
+		c, ok := cols[ds.Name]
+		if !ok {
+			var err error
+			c, err = db.Collection(nil, ds.Name)
+			if err != nil {
+				opts := collectionOptions()
+				c, err = db.CreateCollection(nil, ds.Name, &opts)
+				if err != nil {
+					log.Fatalf("Failed to create collection: %v", err)
+				}
+			} else {
+				checkCollectionOptions(c)
+			}
+			cols[ds.Name] = c
+		}
+		if ds.Documents > 0 {
+			log.Printf("Seeding %d documents into %s...", ds.Documents, ds.Name)
+			l := loadSettings{
+				nrRequests:  ds.Documents,
+				parallelism: evenParallelism(ds.Documents, 16),
+			}
+			l.apply()
+			measuring = false
+			runWorkload(seedDocsWorkload(c))
+			measuring = true
+		}
+	}
+	return cols
+}
+
+// documents returns the number of documents seeded into the dataset
+// name, 0 if it is not seeded.
+func (sc *scenario) documents(name string) int {
+	for _, ds := range sc.Datasets {
+		if ds.Name == name {
+			return ds.Documents
+		}
+	}
+	return 0
+}
+
+// run runs all phases in order. Every phase starts from the settings of
+// the command line and overrides them as given.
+func (sc *scenario) run(c driver.Client, db driver.Database, col driver.Collection) {
+	cols := sc.setupDatasets(db, col)
+	base := currentLoad()
+	defer base.apply()
+
+	for _, p := range sc.Phases {
+		l := base
+		if p.NrRequests > 0 {
+			l.nrRequests = p.NrRequests
+		}
+		if p.Parallelism > 0 {
+			l.parallelism = p.Parallelism
+		}
+		if p.Delay > 0 {
+			l.delay = p.Delay
+		}
+		if p.Rate > 0 {
+			l.rate = p.Rate
+		}
+		if p.Duration > 0 {
+			l.duration = p.Duration
+		}
+		l.apply()
+		target := col
+		if p.Dataset != "" {
+			var ok bool
+			if target, ok = cols[p.Dataset]; !ok {
+				log.Fatalf("Phase %s uses unknown dataset %s", p.Name, p.Dataset)
+			}
+		}
+		savedWaitForSync, savedOverwriteMode := waitForSync, overwriteMode
+		if p.WaitForSync != nil {
+			waitForSync = *p.WaitForSync
+		}
+		if p.OverwriteMode != "" {
+			overwriteMode = p.OverwriteMode
+		}
+		checkDurabilityFlags()
+		// Choose keys among the seeded documents of the dataset.
+		savedKeySpace := keySpace
+		if keySpace == 0 {
+			keySpace = sc.documents(target.Name())
+		}
+
+		log.Printf("Phase %s (%s): %s", p.Name, p.Kind, p.Testcase)
+		currentPhase = p.Name
+		measuring = p.Kind == "measure"
+		if len(p.Workloads) > 0 {
+			runMixed(c, target, p.Workloads, l)
+		} else if p.Testcase != "" {
+			runTestcase(c, target, p.Testcase)
+		}
+		if p.Kind == "teardown" {
+			for name, dc := range cols {
+				if name == "test" {
+					// Dropped at the end like without a scenario.
+					if err := dc.Truncate(nil); err != nil {
+						log.Fatalf("Failed to truncate collection: %v", err)
+					}
+					continue
+				}
+				if err := dc.Remove(nil); err != nil {
+					log.Fatalf("Failed to drop collection: %v", err)
+				}
+				delete(cols, name)
+			}
+		}
+		keySpace = savedKeySpace
+		waitForSync, overwriteMode = savedWaitForSync, savedOverwriteMode
+	}
+	currentPhase = ""
+	measuring = true
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testScenario = `
+endpoints: [https://127.0.0.1:8529, https://127.0.0.1:8539]
+protocol: HTTP2
+useTLS: true
+nrConnections: 16
+auth: {user: root, pass: secret}
+datasets:
+  - name: test
+    documents: 100000
+phases:
+  - {name: warmup, kind: warmup, testcase: readDocs, duration: 10s}
+  - {name: read, testcase: readDocs, parallelism: 16, rate: 5000, duration: 1m}
+  - {testcase: replaceDocs, parallelism: 16, nrRequests: 100000, overwriteMode: replace}
+  - name: mixed
+    duration: 1m
+    workloads:
+      - {testcase: readDocs, parallelism: 12, rate: 4000}
+      - {testcase: replaceDocs, parallelism: 4, rate: 500, keySpace: 1000}
+  - {kind: teardown}
+`
+
+func TestLoadScenario(t *testing.T) {
+	savedEndpoint, savedProtocol, savedTLS := endpoint, protocol, usetls
+	savedConnections, savedUser, savedPass, savedTestcase := nrConnections, username, password, testcase
+	defer func() {
+		endpoint, protocol, usetls = savedEndpoint, savedProtocol, savedTLS
+		nrConnections, username, password, testcase = savedConnections, savedUser, savedPass, savedTestcase
+	}()
+
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	if err := ioutil.WriteFile(path, []byte(testScenario), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sc := loadScenario(path)
+
+	if endpoint != "https://127.0.0.1:8529,https://127.0.0.1:8539" || protocol != "HTTP2" || !usetls ||
+		nrConnections != 16 || username != "root" || password != "secret" {
+		t.Errorf("connection settings not applied: %s %s %v %d %s", endpoint, protocol, usetls, nrConnections, username)
+	}
+	if testcase != "scenario "+path {
+		t.Errorf("testcase = %q", testcase)
+	}
+	if len(sc.Datasets) != 1 || sc.Datasets[0].Name != "test" || sc.Datasets[0].Documents != 100000 {
+		t.Errorf("datasets = %+v", sc.Datasets)
+	}
+
+	want := []struct {
+		name, kind string
+		duration   time.Duration
+		workloads  int
+	}{
+		{"warmup", "warmup", 10 * time.Second, 0},
+		{"read", "measure", time.Minute, 0},
+		{"measure2", "measure", 0, 0},
+		{"mixed", "measure", time.Minute, 2},
+		{"teardown4", "teardown", 0, 0},
+	}
+	if len(sc.Phases) != len(want) {
+		t.Fatalf("got %d phases, want %d", len(sc.Phases), len(want))
+	}
+	for i, w := range want {
+		p := sc.Phases[i]
+		if p.Name != w.name || p.Kind != w.kind || p.Duration != w.duration || len(p.Workloads) != w.workloads {
+			t.Errorf("phase %d = %s %s %v %d workloads, want %s %s %v %d workloads",
+				i, p.Name, p.Kind, p.Duration, len(p.Workloads), w.name, w.kind, w.duration, w.workloads)
+		}
+	}
+	if p := sc.Phases[1]; p.Parallelism != 16 || p.Rate != 5000 {
+		t.Errorf("phase read has parallelism %d and rate %v", p.Parallelism, p.Rate)
+	}
+	if p := sc.Phases[2]; p.NrRequests != 100000 || p.OverwriteMode != "replace" {
+		t.Errorf("phase measure2 has nrRequests %d and overwriteMode %q", p.NrRequests, p.OverwriteMode)
+	}
+	if e := sc.Phases[3].Workloads[1]; e.Testcase != "replaceDocs" || e.Parallelism != 4 || e.Rate != 500 || e.KeySpace != 1000 {
+		t.Errorf("mixed workload = %+v", e)
+	}
+	if sc.documents("test") != 100000 || sc.documents("other") != 0 {
+		t.Errorf("documents = %d, %d", sc.documents("test"), sc.documents("other"))
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	driver "github.com/arangodb/go-driver"
+)
+
+// ArangoSearch testcases. Before the first run the collection "corpus"
+// in benchDB is filled with -searchDocs documents whose text consists of
+// -searchWords words drawn zipfian from a vocabulary of -searchVocabulary
+// made up words, so that some words are common and most are rare. The
+// corpus is kept with -cleanup=false while these settings stay the same.
+// The view gobenchView, an -searchView "arangosearch" view or a
+// "search-alias" view of an inverted index, is kept as well while its
+// definition stays the same. It indexes the text with -searchAnalyzer,
+// which is a built-in analyzer like text_en, or "gobench" for one
+// created from the JSON definition -searchAnalyzerDefinition, e.g.
+//
+//	{"type": "ngram", "properties": {"min": 3, "max": 3, "preserveOriginal": true,
+//	 "streamType": "utf8"}, "features": ["frequency", "norm", "position"]}
+//
+// The testcases are
+//
+//	searchPhrase  two consecutive words of a random document as phrase
+//	searchPrefix  words starting with the first three letters of a word
+//	searchBM25    documents with any of three words, ranked by BM25
+//	searchLag     inserts a document with a new word and waits until a
+//	              search finds it, reporting the insert latency and the
+//	              time until the document was visible separately
+//
+// the first three return the best -searchLimit documents.
+
+const (
+	searchCollection = "corpus"
+	searchViewName   = "gobenchView"
+	searchIndexName  = "gobenchInverted"
+	searchInfoKey    = "info"
+	searchViewKey    = "view"
+)
+
+var (
+	searchDocs               int           = 10000          // documents in the corpus
+	searchWords              int           = 50             // words per document
+	searchVocabulary         int           = 10000          // different words
+	searchView               string        = "arangosearch" // can be "search-alias"
+	searchAnalyzer           string        = "text_en"      // analyzer of the text
+	searchAnalyzerDefinition string        = ""             // JSON of the "gobench" analyzer
+	searchLimit              int           = 10             // results per query
+	searchCommitInterval     time.Duration = 0              // commit interval of the view, 0 for the default
+	searchPollInterval       time.Duration = 10 * time.Millisecond
+)
+
+var syllables = []string{"ka", "lo", "mi", "nu", "pe", "ra", "si", "to", "ve", "zu",
+	"ba", "de", "fi", "go", "hu", "ja", "ke", "li", "mo", "ny"}
+
+// searchWord returns word number k of the vocabulary.
+func searchWord(k int) string {
+	var b strings.Builder
+	for {
+		b.WriteString(syllables[k%len(syllables)])
+		k /= len(syllables)
+		if k == 0 {
+			return b.String()
+		}
+	}
+}
+
+var (
+	searchZipfOnce sync.Once
+	searchZipf     *zipfian
+)
+
+// searchText returns the text of document n, the same every time. It
+// reseeds r, which therefore must not be used for anything else.
+func searchText(r *rand.Rand, n int) []string {
+	searchZipfOnce.Do(func() { searchZipf = newZipfian(searchVocabulary, 0.99) })
+	r.Seed(int64(n))
+	words := make([]string, searchWords)
+	for i := range words {
+		words[i] = searchWord(searchZipf.next(r))
+	}
+	return words
+}
+
+func searchInfo() string {
+	return fmt.Sprintf("%d %d %d", searchDocs, searchWords, searchVocabulary)
+}
+
+// loadCorpus fills the corpus collection unless it holds a corpus with
+// the same settings already.
+func loadCorpus(db driver.Database) driver.Collection {
+	col, err := db.Collection(nil, searchCollection)
+	if err == nil {
+		var info map[string]interface{}
+		if _, err = col.ReadDocument(nil, searchInfoKey, &info); err == nil && info["info"] == searchInfo() {
+			return col
+		}
+		if err = col.Truncate(nil); err != nil {
+			log.Fatalf("Failed to truncate collection: %v", err)
+		}
+	} else {
+		opts := collectionOptions()
+		if col, err = db.CreateCollection(nil, searchCollection, &opts); err != nil {
+			log.Fatalf("Failed to create collection: %v", err)
+		}
+	}
+	log.Printf("Loading %d documents with %d words each...", searchDocs, searchWords)
+	r := rand.New(rand.NewSource(0))
+	for start := 0; start < searchDocs; start += graphLoadBatchSize {
+		var batch []map[string]interface{}
+		for n := start; n < searchDocs && n < start+graphLoadBatchSize; n++ {
+			batch = append(batch, map[string]interface{}{
+				"_key": "S" + strconv.Itoa(n),
+				"text": strings.Join(searchText(r, n), " "),
+			})
+		}
+		if _, errs, err := col.CreateDocuments(nil, batch); batchError(errs, err) != nil {
+			log.Fatalf("Failed to create documents: %v", batchError(errs, err))
+		}
+	}
+	_, err = col.CreateDocument(nil, map[string]interface{}{"_key": searchInfoKey, "info": searchInfo()})
+	if err != nil {
+		log.Fatalf("Failed to create document: %v", err)
+	}
+	return col
+}
+
+// createSearchView creates the analyzer, if needed, and the view, unless
+// they are left over from an earlier run with the same definition. The
+// definition is kept in the document searchViewKey of the corpus, so
+// that a reloaded corpus gets a new view as well.
+func createSearchView(c driver.Client, db driver.Database, corpus driver.Collection) {
+	var analyzer map[string]interface{}
+	if searchAnalyzerDefinition != "" {
+		if searchAnalyzer != "gobench" {
+			log.Fatalf("-searchAnalyzerDefinition needs -searchAnalyzer=gobench")
+		}
+		if err := json.Unmarshal([]byte(searchAnalyzerDefinition), &analyzer); err != nil {
+			log.Fatalf("Invalid -searchAnalyzerDefinition: %v", err)
+		}
+		analyzer["name"] = "gobench"
+	}
+	view := map[string]interface{}{"name": searchViewName, "type": searchView}
+	var index map[string]interface{}
+	commit := map[string]interface{}{}
+	if searchCommitInterval > 0 {
+		commit["commitIntervalMsec"] = searchCommitInterval.Milliseconds()
+	}
+	switch searchView {
+	case "arangosearch":
+		for k, v := range commit {
+			view[k] = v
+		}
+		view["links"] = map[string]interface{}{
+			searchCollection: map[string]interface{}{
+				"fields": map[string]interface{}{
+					"text": map[string]interface{}{"analyzers": []string{searchAnalyzer}},
+				},
+			},
+		}
+	case "search-alias":
+		index = map[string]interface{}{
+			"type":   "inverted",
+			"name":   searchIndexName,
+			"fields": []map[string]interface{}{{"name": "text", "analyzer": searchAnalyzer}},
+		}
+		for k, v := range commit {
+			index[k] = v
+		}
+		view["indexes"] = []map[string]interface{}{{"collection": searchCollection, "index": searchIndexName}}
+	default:
+		log.Fatalf("-searchView needs to be arangosearch or search-alias")
+	}
+	definition, _ := json.Marshal(map[string]interface{}{"analyzer": analyzer, "view": view, "index": index})
+
+	var stored map[string]interface{}
+	var props struct {
+		Type string `json:"type"`
+	}
+	if _, err := corpus.ReadDocument(nil, searchViewKey, &stored); err == nil &&
+		stored["definition"] == string(definition) &&
+		dbRequest(c, db, "GET", "_api/view/"+searchViewName, nil, nil, &props) == nil && props.Type == searchView {
+		log.Printf("Reusing %s", searchViewName)
+	} else {
+		// Whatever is left over from an earlier run is dropped first:
+		dbRequest(c, db, "DELETE", "_api/view/"+searchViewName, nil, nil, nil)
+		dbRequest(c, db, "DELETE", "_api/index/"+searchCollection+"/"+searchIndexName, nil, nil, nil)
+		corpus.RemoveDocument(nil, searchViewKey)
+		if analyzer != nil {
+			dbRequest(c, db, "DELETE", "_api/analyzer/gobench", map[string]string{"force": "true"}, nil, nil)
+			if err := dbRequest(c, db, "POST", "_api/analyzer", nil, analyzer, nil); err != nil {
+				log.Fatalf("Failed to create analyzer: %v", err)
+			}
+		}
+		if index != nil {
+			err := dbRequest(c, db, "POST", "_api/index", map[string]string{"collection": searchCollection}, index, nil)
+			if err != nil {
+				log.Fatalf("Failed to create inverted index: %v", err)
+			}
+		}
+		if err := dbRequest(c, db, "POST", "_api/view", nil, view, nil); err != nil {
+			log.Fatalf("Failed to create view: %v", err)
+		}
+		_, err := corpus.CreateDocument(nil, map[string]interface{}{"_key": searchViewKey, "definition": string(definition)})
+		if err != nil {
+			log.Fatalf("Failed to create document: %v", err)
+		}
+	}
+
+	log.Printf("Waiting for %s to index the corpus...", searchViewName)
+	err := drainQuery(nil, db, "FOR d IN "+searchViewName+" OPTIONS {waitForSync: true} LIMIT 1 RETURN 1", nil)
+	if err != nil {
+		log.Fatalf("Failed to wait for view: %v", err)
+	}
+}
+
+var searchQueries = map[string]struct {
+	name  string
+	query string
+}{
+	"searchPhrase": {"search phrase ops",
+		"FOR d IN " + searchViewName + " SEARCH PHRASE(d.text, @phrase, @analyzer) LIMIT @limit RETURN d._key"},
+	"searchPrefix": {"search prefix ops",
+		"FOR d IN " + searchViewName + " SEARCH ANALYZER(STARTS_WITH(d.text, @prefix), @analyzer) LIMIT @limit RETURN d._key"},
+	"searchBM25": {"search BM25 ops",
+		"FOR d IN " + searchViewName + " SEARCH ANALYZER(d.text IN TOKENS(@words, @analyzer), @analyzer) " +
+			"SORT BM25(d) DESC LIMIT @limit RETURN d._key"},
+	"searchLag": {"search insert until visible ops",
+		"FOR d IN " + searchViewName + " SEARCH PHRASE(d.text, @word, @analyzer) LIMIT 1 RETURN d._key"},
+}
+
+func searchWorkload(c driver.Client, db driver.Database, tc string) workload {
+	if searchDocs < 1 || searchWords < 2 || searchVocabulary < 2 {
+		log.Fatalf("-searchDocs needs to be at least 1, -searchWords and -searchVocabulary at least 2")
+	}
+	corpus := loadCorpus(db)
+	createSearchView(c, db, corpus)
+
+	q := searchQueries[tc]
+	var rands, texts workerRands
+	var ops opTimes
+	op := func(base, i int) error {
+		r := rands.get(base)
+		bindVars := map[string]interface{}{"analyzer": searchAnalyzer}
+		if tc != "searchLag" {
+			bindVars["limit"] = searchLimit
+		}
+		text := searchText(texts.get(base), r.Intn(searchDocs))
+		switch tc {
+		case "searchPhrase":
+			w := r.Intn(len(text) - 1)
+			bindVars["phrase"] = text[w] + " " + text[w+1]
+		case "searchPrefix":
+			word := text[r.Intn(len(text))]
+			if len(word) > 3 {
+				word = word[:3]
+			}
+			bindVars["prefix"] = word
+		case "searchBM25":
+			bindVars["words"] = strings.Join([]string{
+				text[r.Intn(len(text))], text[r.Intn(len(text))], text[r.Intn(len(text))]}, " ")
+		case "searchLag":
+			// A word which is in no other document. It is looked up as
+			// phrase, since analyzers like text_en stem it:
+			word := "lag" + strconv.Itoa(base) + "x" + strconv.Itoa(i) + "x" + strconv.FormatInt(r.Int63(), 36)
+			bindVars["word"] = word
+			startTime := time.Now()
+			_, err := corpus.CreateDocument(insertContext(), map[string]interface{}{"text": word})
+			if err != nil {
+				return err
+			}
+			written := time.Now()
+			ops.add("insert", written.Sub(startTime))
+			for {
+				cur, err := db.Query(nil, q.query, bindVars)
+				if err != nil {
+					return err
+				}
+				found := cur.HasMore()
+				cur.Close()
+				if found {
+					ops.add("visibility lag", time.Since(written))
+					return nil
+				}
+				if time.Since(written) > time.Minute {
+					return fmt.Errorf("document with %s not found after a minute", word)
+				}
+				time.Sleep(searchPollInterval)
+			}
+		}
+		return drainQuery(nil, db, q.query, bindVars)
+	}
+	done := func(par int) {
+		ops.log(tc, "search", par)
+	}
+	return workload{tc, q.name, op, done}
+}
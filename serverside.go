@@ -0,0 +1,123 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"strconv"
+
+	driver "github.com/arangodb/go-driver"
+)
+
+// Server-side logic, to compare with the same logic run by the client:
+//
+// jsTrx runs one JavaScript transaction per request through
+// /_api/transaction. The built-in action reads and updates -trxSize
+// documents chosen like streamTrx does, so that both testcases do the
+// same work with the same settings. -jsTrxFile replaces it with the
+// function in a file, which gets the same parameters: since the driver
+// always sends them as a list, an array with the one object
+// {collection, ops} as its first element, ops being a list of
+// {key, write, n}.
+//
+// aqlFunction registers the user-defined AQL function GOBENCH::FN, from
+// -aqlFunctionFile or a built-in one, and runs -aqlFunctionQuery with it.
+// The bind parameters @@col (the test collection) and @n (-trxSize) are
+// passed if the query uses them. The built-in function only uses
+// attributes every document has, so that it works with -docSize as well.
+
+const builtinTrxAction = `function (params) {
+  const db = require("@arangodb").db;
+  const col = db._collection(params[0].collection);
+  for (const op of params[0].ops) {
+    if (op.write) {
+      col.update(op.key, {no_pages: op.n});
+    } else {
+      col.document(op.key);
+    }
+  }
+}`
+
+const builtinAQLFunction = `function (doc) {
+  return doc._key.length + Object.keys(doc).length;
+}`
+
+const aqlFunctionName = "GOBENCH::FN"
+
+var (
+	jsTrxFile        string = "" // file with the action of jsTrx
+	aqlFunctionFile  string = "" // file with the code of GOBENCH::FN
+	aqlFunctionQuery string = "FOR d IN @@col LIMIT @n RETURN GOBENCH::FN(d)"
+)
+
+// readCode returns the contents of file, or def if file is empty.
+func readCode(file string, def string) string {
+	if file == "" {
+		return def
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", file, err)
+	}
+	return string(data)
+}
+
+func jsTrxWorkload(col driver.Collection) workload {
+	db := col.Database()
+	action := readCode(jsTrxFile, builtinTrxAction)
+	keys := trxKeyChooser()
+	var rands workerRands
+
+	return workload{"jsTrx", "JavaScript transaction ops", func(base, i int) error {
+		r := rands.get(base)
+		ops := make([]map[string]interface{}, trxSize)
+		for j := range ops {
+			ops[j] = map[string]interface{}{
+				"key":   "K" + strconv.Itoa(keys.next(r)),
+				"write": r.Float64() < trxWriteFraction,
+				"n":     i,
+			}
+		}
+		opts := driver.TransactionOptions{
+			WriteCollections: []string{col.Name()},
+			WaitForSync:      waitForSync,
+			Params: []interface{}{map[string]interface{}{
+				"collection": col.Name(),
+				"ops":        ops,
+			}},
+		}
+		_, err := db.Transaction(nil, action, &opts)
+		return err
+	}, nil}
+}
+
+func aqlFunctionWorkload(c driver.Client, col driver.Collection) workload {
+	db := col.Database()
+	code := readCode(aqlFunctionFile, builtinAQLFunction)
+	err := dbRequest(c, db, "POST", "_api/aqlfunction", nil, map[string]interface{}{
+		"name":            aqlFunctionName,
+		"code":            code,
+		"isDeterministic": false,
+	}, nil)
+	if err != nil {
+		log.Fatalf("Failed to register AQL function: %v", err)
+	}
+	bindVars := map[string]interface{}{}
+	if usesBindParameter(aqlFunctionQuery, "@@col") {
+		bindVars["@col"] = col.Name()
+	}
+	if usesBindParameter(aqlFunctionQuery, "@n") {
+		bindVars["n"] = trxSize
+	}
+
+	op := func(base, i int) error {
+		return drainQuery(nil, db, aqlFunctionQuery, bindVars)
+	}
+	done := func(par int) {
+		if cleanup {
+			if err := dbRequest(c, db, "DELETE", "_api/aqlfunction/"+aqlFunctionName, nil, nil, nil); err != nil {
+				log.Fatalf("Failed to remove AQL function: %v", err)
+			}
+		}
+	}
+	return workload{"aqlFunction", "AQL user function ops", op, done}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	driver "github.com/arangodb/go-driver"
+)
+
+// Sharding options of the collections gobench creates, besides
+// -numberOfShards: -shardKeys, -distributeShardsLike and, in the
+// Enterprise Edition, -satellite, -smartJoinAttribute and -smart with
+// -smartGraphAttribute. They only matter in a cluster. The documents of
+// smart collections need the smart graph attribute and keys starting
+// with its value and a colon, which only the shard testcases write.
+//
+// The shardSingle and shardAll testcases seed -shardDocs documents into
+// the test collection, with every shard key attribute set, and then find
+// a random one of them by AQL. shardSingle filters on all shard keys, so
+// that the coordinator only asks the one shard holding the document,
+// shardAll filters on an indexed attribute which is no shard key, so that
+// every shard is asked. Both report the shards of the collection and the
+// servers holding them, as read from the cluster, with their results.
+
+var (
+	shardKeys            string = ""    // comma separated, "" for _key
+	distributeShardsLike string = ""    // collection to distribute shards like
+	satellite            bool   = false // create satellite collections
+	smartJoinAttribute   string = ""    // Enterprise Edition only
+	smart                bool   = false // create smart collections
+	smartGraphAttribute  string = ""    // sharding attribute of smart collections
+	shardDocs            int    = 10000 // documents of shardSingle and shardAll
+)
+
+// shardKeyList are the shard keys, nil for the server default.
+func shardKeyList() []string {
+	if shardKeys == "" {
+		return nil
+	}
+	return strings.Split(shardKeys, ",")
+}
+
+// shardingName lists the sharding settings which differ from the
+// defaults, for titles.
+func shardingName() string {
+	s := ""
+	if shardKeys != "" {
+		s += " shardKeys=" + shardKeys
+	}
+	if distributeShardsLike != "" {
+		s += " distributeShardsLike=" + distributeShardsLike
+	}
+	if satellite {
+		s += " satellite"
+	}
+	if smartJoinAttribute != "" {
+		s += " smartJoinAttribute=" + smartJoinAttribute
+	}
+	if smart {
+		s += " smartGraphAttribute=" + smartGraphAttribute
+	}
+	return s
+}
+
+func checkShardingFlags() {
+	if smart && smartGraphAttribute == "" {
+		log.Fatalf("-smart needs -smartGraphAttribute")
+	}
+	if smart && (satellite || shardKeys != "") {
+		log.Fatalf("-smart does not work with -satellite or -shardKeys")
+	}
+}
+
+// smartGroups is the number of values of the smart graph attribute of
+// the shard testcases, documents with the same value share a shard.
+const smartGroups = 100
+
+// shardDistribution describes how the shards of col are spread over the
+// DB servers, e.g. "3 shards on 3 servers", and logs the leader and the
+// number of documents of every shard. Single servers have no shards.
+func shardDistribution(c driver.Client, col driver.Collection) string {
+	cl, err := c.Cluster(nil)
+	if driver.IsPreconditionFailed(err) {
+		return "single server"
+	}
+	if err != nil {
+		log.Fatalf("Failed to access cluster: %v", err)
+	}
+	inv, err := cl.DatabaseInventory(nil, col.Database())
+	if err != nil {
+		// The driver cannot read the inventory of satellite collections.
+		log.Printf("Failed to read database inventory: %v", err)
+		return "unknown shards"
+	}
+	ic, ok := inv.CollectionByName(col.Name())
+	if !ok {
+		log.Fatalf("Collection %s is not in the inventory", col.Name())
+	}
+	var counts struct {
+		Count map[string]int64 `json:"count"`
+	}
+	err = dbRequest(c, col.Database(), "GET", "_api/collection/"+col.Name()+"/count",
+		map[string]string{"details": "true"}, nil, &counts)
+	if err != nil {
+		log.Fatalf("Failed to read shard counts: %v", err)
+	}
+
+	shards := make([]string, 0, len(ic.Parameters.Shards))
+	for id := range ic.Parameters.Shards {
+		shards = append(shards, string(id))
+	}
+	sort.Strings(shards)
+	servers := map[driver.ServerID]bool{}
+	for _, id := range shards {
+		replicas := ic.Parameters.Shards[driver.ShardID(id)]
+		for _, s := range replicas {
+			servers[s] = true
+		}
+		leader := driver.ServerID("none")
+		if len(replicas) > 0 {
+			leader = replicas[0]
+		}
+		log.Printf("Shard %s of %s: leader %s, %d replicas, %d documents",
+			id, col.Name(), leader, len(replicas), counts.Count[id])
+	}
+	return fmt.Sprintf("%d shards on %d servers", len(shards), len(servers))
+}
+
+// shardDoc is document n of the shard testcases.
+func shardDoc(n int) map[string]interface{} {
+	doc := map[string]interface{}{"_key": "K" + strconv.Itoa(n), "n": n}
+	if smart {
+		value := "G" + strconv.Itoa(n%smartGroups)
+		doc["_key"] = value + ":K" + strconv.Itoa(n)
+		doc[smartGraphAttribute] = value
+	}
+	for _, k := range shardKeyList() {
+		if k = strings.TrimSuffix(k, ":"); k != "_key" {
+			doc[k] = "v" + strconv.Itoa(n)
+		}
+	}
+	return doc
+}
+
+func shardWorkload(c driver.Client, col driver.Collection, tc string) workload {
+	if shardDocs < 1 {
+		log.Fatalf("-shardDocs needs to be at least 1")
+	}
+	log.Printf("Seeding %d documents into %s...", shardDocs, col.Name())
+	ctx := driver.WithOverwriteMode(insertContext(), driver.OverwriteModeReplace)
+	for start := 0; start < shardDocs; start += graphLoadBatchSize {
+		var batch []map[string]interface{}
+		for n := start; n < shardDocs && n < start+graphLoadBatchSize; n++ {
+			batch = append(batch, shardDoc(n))
+		}
+		if _, errs, err := col.CreateDocuments(ctx, batch); batchError(errs, err) != nil {
+			log.Fatalf("Failed to create documents: %v", batchError(errs, err))
+		}
+	}
+	_, _, err := col.EnsurePersistentIndex(nil, []string{"n"}, &driver.EnsurePersistentIndexOptions{Name: "gobenchN"})
+	if err != nil {
+		log.Fatalf("Failed to create index: %v", err)
+	}
+
+	query := "FOR d IN @@col FILTER d.n == @n RETURN d"
+	name := "shard all ops"
+	if tc == "shardSingle" {
+		var filters []string
+		keys := shardKeyList()
+		if keys == nil {
+			keys = []string{"_key"}
+		}
+		for _, k := range keys {
+			k = strings.TrimSuffix(k, ":")
+			filters = append(filters, fmt.Sprintf("d.`%s` == @doc.`%s`", k, k))
+		}
+		query = "FOR d IN @@col FILTER " + strings.Join(filters, " && ") + " RETURN d"
+		name = "shard single ops"
+	}
+	name += " (" + shardDistribution(c, col) + ")"
+
+	keys := newKeyChooser(keyDistribution, shardDocs, nil)
+	if keys == nil {
+		keys = uniformKeys{shardDocs}
+	}
+	var rands workerRands
+	db := col.Database()
+	op := func(base, i int) error {
+		n := keys.next(rands.get(base))
+		bindVars := map[string]interface{}{"@col": col.Name()}
+		if tc == "shardSingle" {
+			bindVars["doc"] = shardDoc(n)
+		} else {
+			bindVars["n"] = n
+		}
+		return drainQuery(nil, db, query, bindVars)
+	}
+	return workload{tc, name, op, nil}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"log"
+	"strconv"
+
+	driver "github.com/arangodb/go-driver"
+)
+
+// The tenant testcases spread the load over -tenants tenants, each with
+// its own collection "tenant<n>" in benchDB, or with
+// -tenantMode=databases its own database "benchTenant<n>" with a
+// collection "data". Missing tenants are created first and seeded with
+// -tenantDocs documents "K0", "K1", ..., which is reported as "tenant
+// setup" per tenant. Every request picks a tenant by -tenantDistribution
+// and a document of it by -keyDistribution, uniformly for both unless
+// given:
+//
+//	tenantRead     reads the document
+//	tenantReplace  replaces the document
+//
+// With -cleanup the tenants are dropped afterwards, reported as "tenant
+// teardown", with -cleanup=false they are kept for further runs.
+
+var (
+	tenants            int    = 100           // number of tenants
+	tenantMode         string = "collections" // or "databases"
+	tenantDocs         int    = 100           // documents per tenant
+	tenantDistribution string = "uniform"     // how requests are spread over the tenants
+)
+
+// tenantSet are the collections of all tenants.
+type tenantSet struct {
+	c    driver.Client
+	db   driver.Database
+	cols []driver.Collection
+}
+
+func tenantDatabaseName(n int) string {
+	return "benchTenant" + strconv.Itoa(n)
+}
+
+// create returns the collection of tenant n, creating and seeding it if
+// it does not exist.
+func (t *tenantSet) create(n int, docs *docSource, base int) (driver.Collection, error) {
+	db, name := t.db, "tenant"+strconv.Itoa(n)
+	if tenantMode == "databases" {
+		var err error
+		name = "data"
+		if db, err = t.c.Database(nil, tenantDatabaseName(n)); driver.IsNotFound(err) {
+			db, err = t.c.CreateDatabase(nil, tenantDatabaseName(n), nil)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	col, err := db.Collection(nil, name)
+	if err == nil {
+		return col, nil
+	}
+	if !driver.IsNotFound(err) {
+		return nil, err
+	}
+	opts := collectionOptions()
+	if col, err = db.CreateCollection(nil, name, &opts); err != nil {
+		return nil, err
+	}
+	batch := make([]interface{}, 0, tenantDocs)
+	for k := 0; k < tenantDocs; k++ {
+		batch = append(batch, docs.document(base, k, "K"+strconv.Itoa(k)))
+	}
+	_, errs, err := col.CreateDocuments(insertContext(), batch)
+	return col, batchError(errs, err)
+}
+
+// newTenantSet creates the missing tenants.
+func newTenantSet(c driver.Client, db driver.Database) *tenantSet {
+	if tenants < 1 || tenantDocs < 1 {
+		log.Fatalf("-tenants and -tenantDocs need to be at least 1")
+	}
+	if tenantMode != "collections" && tenantMode != "databases" {
+		log.Fatalf("-tenantMode needs to be collections or databases")
+	}
+	t := &tenantSet{c: c, db: db, cols: make([]driver.Collection, tenants)}
+	log.Printf("Setting up %d tenants...", tenants)
+	docs := newDocSource()
+	l := loadSettings{
+		nrRequests:  tenants,
+		parallelism: evenParallelism(tenants, parallelism),
+	}
+	times := runWorkers("tenantSetup", l, func(base, i int) error {
+		col, err := t.create(base+i, docs, base)
+		t.cols[base+i] = col
+		return err
+	})
+	logStats("tenantSetup", "tenant setup", l.parallelism, times)
+	return t
+}
+
+// teardown drops all tenants.
+func (t *tenantSet) teardown() {
+	l := loadSettings{
+		nrRequests:  tenants,
+		parallelism: evenParallelism(tenants, parallelism),
+	}
+	times := runWorkers("tenantTeardown", l, func(base, i int) error {
+		if tenantMode == "databases" {
+			return t.cols[base+i].Database().Remove(nil)
+		}
+		return t.cols[base+i].Remove(nil)
+	})
+	logStats("tenantTeardown", "tenant teardown", l.parallelism, times)
+}
+
+func tenantWorkload(c driver.Client, db driver.Database, tc string) workload {
+	t := newTenantSet(c, db)
+	tenantChooser := newKeyChooser(tenantDistribution, tenants, nil)
+	if tenantChooser == nil {
+		tenantChooser = uniformKeys{tenants}
+	}
+	dist := keyDistribution
+	if dist == "sequential" {
+		dist = "uniform"
+	}
+	keys := newKeyChooser(dist, tenantDocs, nil)
+	docs := newDocSource()
+	var rands workerRands
+
+	name := "tenant read ops"
+	if tc == "tenantReplace" {
+		name = "tenant replace ops"
+	}
+	op := func(base, i int) error {
+		r := rands.get(base)
+		col := t.cols[tenantChooser.next(r)]
+		key := "K" + strconv.Itoa(keys.next(r))
+		if tc == "tenantReplace" {
+			_, err := col.ReplaceDocument(writeContext(), key, docs.document(base, i, key))
+			return err
+		}
+		var doc map[string]interface{}
+		_, err := col.ReadDocument(nil, key, &doc)
+		return err
+	}
+	done := func(par int) {
+		if cleanup {
+			t.teardown()
+		}
+	}
+	return workload{tc, name, op, done}
+}
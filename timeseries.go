@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	driver "github.com/arangodb/go-driver"
+)
+
+// The timeseries testcase models metrics ingestion. The collection
+// "timeseries" is created empty, with a persistent index on sensor and
+// time, and with -tsIndex=ttl additionally a TTL index removing points
+// -tsRetention after their time. A fraction -tsQueryFraction of the
+// requests aggregate the points of the last -tsWindow, of a random
+// sensor with -tsQuery=sensor or grouped by sensor over all of them with
+// -tsQuery=all, the others insert -tsBatchSize points {sensor, time,
+// value} of random ones of -tsSensors sensors. Since the collection
+// grows during the run, best run with -duration, the query latencies are
+// reported per -tsInterval of the run, together with the number of
+// points inserted up to then, which shows how the queries slow down as
+// the data accumulate. The ingestion rate is reported in points/s.
+
+const tsCollection = "timeseries"
+
+var (
+	tsSensors       int           = 1000             // number of sensors
+	tsBatchSize     int           = 100              // points per insert
+	tsQueryFraction float64       = 0.1              // fraction of the requests which are queries
+	tsQuery         string        = "sensor"         // or "all"
+	tsWindow        time.Duration = 10 * time.Second // time range of the queries
+	tsIndex         string        = "persistent"     // or "ttl"
+	tsRetention     time.Duration = time.Minute      // expiry of the TTL index
+	tsInterval      time.Duration = 10 * time.Second // reporting interval of the query latencies
+)
+
+var tsQueries = map[string]string{
+	"sensor": "FOR p IN @@col FILTER p.sensor == @sensor && p.time >= @from " +
+		"COLLECT AGGREGATE n = COUNT(1), avg = AVG(p.value), max = MAX(p.value) RETURN {n, avg, max}",
+	"all": "FOR p IN @@col FILTER p.time >= @from " +
+		"COLLECT sensor = p.sensor AGGREGATE n = COUNT(1), avg = AVG(p.value), max = MAX(p.value) " +
+		"RETURN {sensor, n, avg, max}",
+}
+
+type tsPoint struct {
+	Sensor string  `json:"sensor"`
+	Time   float64 `json:"time"` // seconds since the epoch, as TTL indexes want it
+	Value  float64 `json:"value"`
+}
+
+// tsCreateCollection creates the collection anew with its indexes.
+func tsCreateCollection(db driver.Database) driver.Collection {
+	if col, err := db.Collection(nil, tsCollection); err == nil {
+		if err = col.Remove(nil); err != nil {
+			log.Fatalf("Failed to drop collection: %v", err)
+		}
+	}
+	opts := collectionOptions()
+	col, err := db.CreateCollection(nil, tsCollection, &opts)
+	if err != nil {
+		log.Fatalf("Failed to create collection: %v", err)
+	}
+	fields := []string{"sensor", "time"}
+	if tsQuery == "all" {
+		fields = []string{"time"}
+	}
+	_, _, err = col.EnsurePersistentIndex(nil, fields, &driver.EnsurePersistentIndexOptions{Name: "gobenchTime"})
+	if err != nil {
+		log.Fatalf("Failed to create index: %v", err)
+	}
+	if tsIndex == "ttl" {
+		_, _, err = col.EnsureTTLIndex(nil, "time", int(tsRetention.Seconds()),
+			&driver.EnsureTTLIndexOptions{Name: "gobenchTTL"})
+		if err != nil {
+			log.Fatalf("Failed to create index: %v", err)
+		}
+	}
+	return col
+}
+
+func timeseriesWorkload(col driver.Collection) workload {
+	if tsSensors < 1 || tsBatchSize < 1 || tsInterval <= 0 {
+		log.Fatalf("-tsSensors and -tsBatchSize need to be at least 1, -tsInterval positive")
+	}
+	query, ok := tsQueries[tsQuery]
+	if !ok {
+		log.Fatalf("-tsQuery needs to be sensor or all")
+	}
+	if tsIndex != "persistent" && tsIndex != "ttl" {
+		log.Fatalf("-tsIndex needs to be persistent or ttl")
+	}
+	db := col.Database()
+	ts := tsCreateCollection(db)
+
+	var rands workerRands
+	var ops opTimes
+	var once sync.Once
+	var start time.Time
+	points := int64(0)
+	var mutex sync.Mutex
+	pointsAt := map[int]int64{} // points inserted by the end of an interval
+
+	op := func(base, i int) error {
+		once.Do(func() { start = time.Now() })
+		r := rands.get(base)
+		startTime := time.Now()
+		if r.Float64() < tsQueryFraction {
+			from := float64(startTime.Add(-tsWindow).UnixNano()) / 1e9
+			bindVars := map[string]interface{}{"@col": ts.Name(), "from": from}
+			if tsQuery == "sensor" {
+				bindVars["sensor"] = "s" + strconv.Itoa(r.Intn(tsSensors))
+			}
+			if err := drainQuery(nil, db, query, bindVars); err != nil {
+				return err
+			}
+			interval := int(startTime.Sub(start) / tsInterval)
+			ops.add(fmt.Sprintf("query %v-%v", time.Duration(interval)*tsInterval,
+				time.Duration(interval+1)*tsInterval), time.Since(startTime))
+			return nil
+		}
+		batch := make([]tsPoint, tsBatchSize)
+		for j := range batch {
+			batch[j] = tsPoint{
+				Sensor: "s" + strconv.Itoa(r.Intn(tsSensors)),
+				Time:   float64(time.Now().UnixNano()) / 1e9,
+				Value:  r.NormFloat64(),
+			}
+		}
+		if _, errs, err := ts.CreateDocuments(insertContext(), batch); batchError(errs, err) != nil {
+			return batchError(errs, err)
+		}
+		ops.add("insert", time.Since(startTime))
+		n := atomic.AddInt64(&points, int64(tsBatchSize))
+		interval := int(time.Since(start) / tsInterval)
+		mutex.Lock()
+		if pointsAt[interval] < n {
+			pointsAt[interval] = n
+		}
+		mutex.Unlock()
+		return nil
+	}
+	done := func(par int) {
+		ops.log("timeseries", "timeseries", par)
+		if !measuring || points == 0 {
+			return
+		}
+		elapsed := time.Since(start)
+		log.Printf("Timeseries: %d points in %v, %.0f points/s", points, elapsed, float64(points)/elapsed.Seconds())
+		last := int64(0)
+		for interval := 0; interval <= int(elapsed/tsInterval); interval++ {
+			if pointsAt[interval] > last {
+				last = pointsAt[interval]
+			}
+			log.Printf("Timeseries: %d points after %v", last, time.Duration(interval+1)*tsInterval)
+		}
+	}
+	return workload{"timeseries", "timeseries ops", op, done}
+}
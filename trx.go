@@ -0,0 +1,124 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	driver "github.com/arangodb/go-driver"
+)
+
+// The streamTrx testcase runs stream transactions on the seeded documents
+// of the test collection: each request of a worker begins a transaction,
+// reads or updates -trxSize documents in it, a fraction -trxWriteFraction
+// of them updates, and commits, or aborts with probability
+// -trxAbortFraction. The contention grows as -trxKeys, the number of keys
+// the transactions choose from, shrinks. Besides the latencies of whole
+// transactions the begin, read, update, commit and abort latencies are
+// reported separately, as well as how many transactions failed with a
+// write-write conflict.
+
+var (
+	trxSize          int     = 10  // operations per transaction
+	trxWriteFraction float64 = 0.5 // fraction of the operations which are updates
+	trxAbortFraction float64 = 0   // fraction of the transactions which are aborted
+	trxKeys          int     = 0   // keys to choose from, 0 for -keySpace or nrRequests
+)
+
+// trxKeyChooser chooses the keys of the documents in transactions.
+func trxKeyChooser() keyChooser {
+	n := trxKeys
+	if n <= 0 {
+		n = keySpace
+	}
+	if n <= 0 {
+		n = nrRequests
+	}
+	dist := keyDistribution
+	if dist == "sequential" {
+		dist = "uniform"
+	}
+	return newKeyChooser(dist, n, nil)
+}
+
+func streamTrxWorkload(col driver.Collection) workload {
+	db := col.Database()
+	keys := trxKeyChooser()
+	var rands workerRands
+	var ops opTimes
+	committed, aborted, conflicts := int64(0), int64(0), int64(0)
+
+	timed := func(kind string, f func() error) error {
+		startTime := time.Now()
+		err := f()
+		if err == nil {
+			ops.add(kind, time.Since(startTime))
+		}
+		return err
+	}
+	op := func(base, i int) error {
+		r := rands.get(base)
+		var tid driver.TransactionID
+		err := timed("begin", func() error {
+			var err error
+			tid, err = db.BeginTransaction(nil, driver.TransactionCollections{
+				Write: []string{col.Name()},
+			}, &driver.BeginTransactionOptions{WaitForSync: waitForSync})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		ctx := driver.WithTransactionID(writeContext(), tid)
+		for j := 0; j < trxSize && err == nil; j++ {
+			key := "K" + strconv.Itoa(keys.next(r))
+			if r.Float64() < trxWriteFraction {
+				err = timed("update", func() error {
+					_, err := col.UpdateDocument(ctx, key, map[string]interface{}{"no_pages": i})
+					return err
+				})
+			} else {
+				err = timed("read", func() error {
+					var book Book
+					_, err := col.ReadDocument(ctx, key, &book)
+					return err
+				})
+			}
+		}
+		if driver.IsConflict(err) {
+			atomic.AddInt64(&conflicts, 1)
+			atomic.AddInt64(&aborted, 1)
+			// The server may have aborted the transaction already.
+			timed("abort", func() error {
+				return db.AbortTransaction(nil, tid, nil)
+			})
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if r.Float64() < trxAbortFraction {
+			atomic.AddInt64(&aborted, 1)
+			return timed("abort", func() error {
+				return db.AbortTransaction(nil, tid, nil)
+			})
+		}
+		atomic.AddInt64(&committed, 1)
+		return timed("commit", func() error {
+			return db.CommitTransaction(nil, tid, nil)
+		})
+	}
+	done := func(par int) {
+		ops.log("streamTrx", "stream transaction", par)
+		if !measuring {
+			return
+		}
+		total := committed + aborted
+		if total > 0 {
+			log.Printf("Stream transactions: %d committed, %d aborted, %d of them by conflicts (%.2f%%)",
+				committed, aborted, conflicts, float64(conflicts)*100/float64(total))
+		}
+	}
+	return workload{"streamTrx", "stream transaction ops", op, done}
+}
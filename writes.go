@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	driver "github.com/arangodb/go-driver"
+)
+
+// Options of the write testcases replaceDocs, updateDocs and removeDocs,
+// they are added to the names of the statistics so that runs with
+// different options can be told apart. With -ifMatch every write sends
+// the revision of the document as it was last seen by gobench. The
+// revisions of the seeded documents are read before the workers start,
+// so that the reads are not part of the measured writes; documents which
+// did not exist then are written without revision.
+
+var (
+	returnOld bool = false // ask for the old document
+	returnNew bool = false // ask for the new document
+	ifMatch   bool = false // only write the last seen revision
+	silent    bool = false // ask for an empty response
+)
+
+// writeOptionsName lists the write options which are set, for the names
+// of the statistics.
+func writeOptionsName() string {
+	var opts []string
+	for _, o := range []struct {
+		set  bool
+		name string
+	}{{returnOld, "returnOld"}, {returnNew, "returnNew"}, {ifMatch, "ifMatch"}, {silent, "silent"}} {
+		if o.set {
+			opts = append(opts, o.name)
+		}
+	}
+	if len(opts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(opts, ", ") + ")"
+}
+
+// writeOptions applies the write options to the requests of a workload
+// and remembers the revisions for -ifMatch.
+type writeOptions struct {
+	col                driver.Collection
+	tc                 string
+	revs               sync.Map // key -> last seen revision
+	missing            int64    // writes of documents which did not exist
+	preconditionFailed int64    // writes of documents changed by someone else
+}
+
+// newWriteOptions prepares the writes of testcase tc to the seeded
+// documents "K0" to "K<n-1>" of col, -keySpace of them if that is set.
+func newWriteOptions(col driver.Collection, tc string, n int) *writeOptions {
+	if ifMatch && silent {
+		log.Fatalf("-ifMatch needs the revisions of the responses and does not work with -silent")
+	}
+	w := &writeOptions{col: col, tc: tc}
+	if ifMatch {
+		if keySpace > 0 {
+			n = keySpace
+		}
+		w.readRevisions(n)
+	}
+	return w
+}
+
+// readRevisions remembers the revisions of the documents "K0" to
+// "K<n-1>" which exist.
+func (w *writeOptions) readRevisions(n int) {
+	for start := 0; start < n; start += batchSize {
+		var keys []string
+		for k := start; k < n && k < start+batchSize; k++ {
+			keys = append(keys, "K"+strconv.Itoa(k))
+		}
+		docs := make([]map[string]interface{}, len(keys))
+		metas, errs, err := w.col.ReadDocuments(nil, keys, docs)
+		if err != nil {
+			log.Fatalf("Failed to read revisions: %v", err)
+		}
+		for j, meta := range metas {
+			if errs[j] == nil && meta.Rev != "" {
+				w.revs.Store(keys[j], meta.Rev)
+			}
+		}
+	}
+}
+
+// context returns the context for a write of key.
+func (w *writeOptions) context(key string) context.Context {
+	ctx := writeContext()
+	if returnOld {
+		var old map[string]interface{}
+		ctx = driver.WithReturnOld(ctx, &old)
+	}
+	if returnNew {
+		var doc map[string]interface{}
+		ctx = driver.WithReturnNew(ctx, &doc)
+	}
+	if silent {
+		ctx = driver.WithSilent(ctx)
+	}
+	if ifMatch {
+		if rev, ok := w.revs.Load(key); ok {
+			ctx = driver.WithRevision(ctx, rev.(string))
+		}
+	}
+	return ctx
+}
+
+// result records the outcome of a write of key. Missing documents and
+// failed preconditions are counted, in the metrics as errors as well,
+// instead of failing the run.
+func (w *writeOptions) result(key string, meta driver.DocumentMeta, err error) error {
+	switch {
+	case driver.IsNotFound(err):
+		atomic.AddInt64(&w.missing, 1)
+		w.revs.Delete(key)
+		countError(w.tc)
+		return nil
+	case driver.IsPreconditionFailed(err):
+		atomic.AddInt64(&w.preconditionFailed, 1)
+		w.revs.Delete(key)
+		countError(w.tc)
+		return nil
+	case err != nil:
+		return err
+	}
+	if ifMatch {
+		if meta.Rev != "" {
+			w.revs.Store(key, meta.Rev)
+		} else {
+			w.revs.Delete(key)
+		}
+	}
+	return nil
+}
+
+// done reports the counted failures of the testcase tc.
+func (w *writeOptions) done(tc string) func(par int) {
+	return func(par int) {
+		if w.missing > 0 {
+			log.Printf("%s: %d documents did not exist", tc, w.missing)
+		}
+		if w.preconditionFailed > 0 {
+			log.Printf("%s: %d revisions did not match", tc, w.preconditionFailed)
+		}
+	}
+}
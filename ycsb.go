@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	driver "github.com/arangodb/go-driver"
+)
+
+// YCSB core workloads A to F, modelled after the Yahoo! Cloud Serving
+// Benchmark so that the numbers can be compared with published results:
+//
+//	A  50% read, 50% update                 zipfian
+//	B  95% read, 5% update                  zipfian
+//	C  100% read                            zipfian
+//	D  95% read, 5% insert                  latest
+//	E  95% scan, 5% insert                  zipfian, scans of 1..100 records
+//	F  50% read, 50% read-modify-write      zipfian
+//
+// Records have the keys "user<hash>" and ycsbFieldCount fields field0,
+// field1, ... of ycsbFieldLength random characters, the defaults are the
+// YCSB ones. Before the first run the records are loaded into the
+// collection, which is reported as "ycsb load". Updates write a single
+// field, reads and scans return whole records. Keys are chosen as
+// listed above unless -keyDistribution is given.
+
+var (
+	ycsbRecordCount int = 1000
+	ycsbFieldCount  int = 10
+	ycsbFieldLength int = 100
+)
+
+// ycsbMix are the proportions of the operations of a YCSB workload.
+type ycsbMix struct {
+	read, update, insert, scan, readModifyWrite float64
+	latest                                      bool // latest instead of zipfian keys
+}
+
+var ycsbWorkloads = map[string]ycsbMix{
+	"A": {read: 0.5, update: 0.5},
+	"B": {read: 0.95, update: 0.05},
+	"C": {read: 1},
+	"D": {read: 0.95, insert: 0.05, latest: true},
+	"E": {scan: 0.95, insert: 0.05},
+	"F": {read: 0.5, readModifyWrite: 0.5},
+}
+
+const ycsbMaxScanLength = 100
+
+// ycsbKey is the key of record number n, hashed like YCSB does so that
+// the records are not inserted in key order.
+func ycsbKey(n int) string {
+	return "user" + strconv.FormatUint(fnvHash(n), 10)
+}
+
+const ycsbChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func ycsbValue(r *rand.Rand) string {
+	b := make([]byte, ycsbFieldLength)
+	for i := range b {
+		b[i] = ycsbChars[r.Intn(len(ycsbChars))]
+	}
+	return string(b)
+}
+
+func ycsbRecord(r *rand.Rand, n int) map[string]string {
+	rec := map[string]string{"_key": ycsbKey(n)}
+	for f := 0; f < ycsbFieldCount; f++ {
+		rec["field"+strconv.Itoa(f)] = ycsbValue(r)
+	}
+	return rec
+}
+
+// opTimes collects latencies per kind of operation.
+type opTimes struct {
+	mutex sync.Mutex
+	times map[string][]time.Duration
+	order []string
+}
+
+func (o *opTimes) add(op string, d time.Duration) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.times == nil {
+		o.times = map[string][]time.Duration{}
+	}
+	if _, ok := o.times[op]; !ok {
+		o.order = append(o.order, op)
+	}
+	o.times[op] = append(o.times[op], d)
+}
+
+// log reports the latencies of every kind of operation separately.
+func (o *opTimes) log(tc string, prefix string, par int) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for _, op := range o.order {
+		logStats(tc, prefix+" "+op+" ops", par, o.times[op])
+	}
+}
+
+// ycsbLoad inserts the records unless the last of them exists already.
+func ycsbLoad(col driver.Collection) {
+	found, err := col.DocumentExists(nil, ycsbKey(ycsbRecordCount-1))
+	if err != nil {
+		log.Fatalf("Failed to look for YCSB records: %v", err)
+	}
+	if found {
+		return
+	}
+	log.Printf("Loading %d YCSB records...", ycsbRecordCount)
+	var rands workerRands
+	l := loadSettings{
+		nrRequests:  ycsbRecordCount,
+		parallelism: evenParallelism(ycsbRecordCount, parallelism),
+	}
+	times := runWorkers("ycsbLoad", l, func(base, i int) error {
+		_, err := col.CreateDocument(nil, ycsbRecord(rands.get(base), base+i))
+		return err
+	})
+	logStats("ycsbLoad", "ycsb load", l.parallelism, times)
+}
+
+// ycsbWorkload prepares YCSB workload name, one of "A" to "F".
+func ycsbWorkload(col driver.Collection, name string) workload {
+	mix := ycsbWorkloads[name]
+	db := col.Database()
+	ycsbLoad(col)
+
+	var rands workerRands
+	var ops opTimes
+	inserted := int64(0)     // inserts started
+	acknowledged := int64(0) // inserts finished
+	misses := int64(0)       // reads of records whose insert was not finished
+
+	dist := keyDistribution
+	if dist == "sequential" {
+		dist = "zipfian"
+		if mix.latest {
+			dist = "latest"
+		}
+	}
+	keys := newKeyChooser(dist, ycsbRecordCount, func() int {
+		return ycsbRecordCount + int(atomic.LoadInt64(&acknowledged)) - 1
+	})
+	chooseKey := func(r *rand.Rand) string {
+		return ycsbKey(keys.next(r))
+	}
+	read := func(key string) error {
+		var rec map[string]interface{}
+		_, err := col.ReadDocument(nil, key, &rec)
+		if driver.IsNotFound(err) && mix.latest {
+			atomic.AddInt64(&misses, 1)
+			return nil
+		}
+		return err
+	}
+	update := func(r *rand.Rand, key string) error {
+		field := "field" + strconv.Itoa(r.Intn(ycsbFieldCount))
+		_, err := col.UpdateDocument(writeContext(), key, map[string]string{field: ycsbValue(r)})
+		return err
+	}
+	insert := func(r *rand.Rand) error {
+		for {
+			n := ycsbRecordCount + int(atomic.AddInt64(&inserted, 1)) - 1
+			_, err := col.CreateDocument(insertContext(), ycsbRecord(r, n))
+			if driver.IsConflict(err) {
+				// Left over from an earlier run, take the next one.
+				continue
+			}
+			if err == nil {
+				atomic.AddInt64(&acknowledged, 1)
+			}
+			return err
+		}
+	}
+	scan := func(r *rand.Rand, key string) error {
+		query := "FOR d IN @@col FILTER d._key >= @start SORT d._key LIMIT @n RETURN d"
+		bindVars := map[string]interface{}{
+			"@col":  col.Name(),
+			"start": key,
+			"n":     1 + r.Intn(ycsbMaxScanLength),
+		}
+		return drainQuery(nil, db, query, bindVars)
+	}
+
+	op := func(base, i int) error {
+		r := rands.get(base)
+		startTime := time.Now()
+		var kind string
+		var err error
+		switch p := r.Float64(); {
+		case p < mix.read:
+			kind = "read"
+			err = read(chooseKey(r))
+		case p < mix.read+mix.update:
+			kind = "update"
+			err = update(r, chooseKey(r))
+		case p < mix.read+mix.update+mix.insert:
+			kind = "insert"
+			err = insert(r)
+		case p < mix.read+mix.update+mix.insert+mix.scan:
+			kind = "scan"
+			err = scan(r, chooseKey(r))
+		default:
+			kind = "read-modify-write"
+			key := chooseKey(r)
+			if err = read(key); err == nil {
+				err = update(r, key)
+			}
+		}
+		if err == nil {
+			ops.add(kind, time.Since(startTime))
+		}
+		return err
+	}
+	done := func(par int) {
+		ops.log("ycsb"+name, "ycsb "+name, par)
+		if misses > 0 {
+			log.Printf("YCSB %s: %d reads of records not yet inserted", name, misses)
+		}
+	}
+	return workload{"ycsb" + name, fmt.Sprintf("ycsb %s ops", name), op, done}
+}